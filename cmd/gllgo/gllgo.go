@@ -29,11 +29,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"llvm.org/llgo/debug"
 	"llvm.org/llgo/driver"
 	"llvm.org/llgo/irgen"
+	"llvm.org/llgo/ssaopt"
 	"llvm.org/llvm/bindings/go/llvm"
 )
 
@@ -58,7 +60,7 @@ func displayVersion() {
 	os.Exit(0)
 }
 
-func initCompiler(opts *driverOptions) (*irgen.Compiler, error) {
+func initCompiler(opts *driverOptions, splitDwarfFile string) (*irgen.Compiler, error) {
 	importPaths := make([]string, len(opts.importPaths)+len(opts.libPaths))
 	copy(importPaths, opts.importPaths)
 	copy(importPaths[len(opts.importPaths):], opts.libPaths)
@@ -66,14 +68,27 @@ func initCompiler(opts *driverOptions) (*irgen.Compiler, error) {
 		importPaths = append(importPaths, filepath.Join(opts.prefix, "lib"+LibDirSuffix, "go", "llgo-"+llvmVersion()))
 	}
 	copts := irgen.CompilerOptions{
-		TargetTriple:       opts.triple,
-		GenerateDebug:      opts.generateDebug,
-		DebugPrefixMaps:    opts.debugPrefixMaps,
-		DumpSSA:            opts.dumpSSA,
-		GccgoPath:          opts.gccgoPath,
-		GccgoABI:           opts.gccgoPath != "",
-		ImportPaths:        importPaths,
-		SanitizerAttribute: opts.sanitizer.getAttribute(),
+		TargetTriple:         opts.triple,
+		GenerateDebug:        opts.generateDebug,
+		DebugPrefixMaps:      opts.debugPrefixMaps,
+		SplitDwarfFile:       splitDwarfFile,
+		GdbScript:            opts.gdbScript(),
+		DumpSSA:              opts.dumpSSA,
+		GccgoPath:            opts.gccgoPath,
+		GccgoABI:             opts.gccgoPath != "",
+		ImportPaths:          importPaths,
+		SanitizerAttribute:   opts.sanitizer.getAttribute(),
+		StackProtector:       opts.stackProtector,
+		NoSplitStack:         opts.noSplitStack,
+		OptimizeSiblingCalls: opts.optSiblingCalls,
+		TargetCPU:            opts.targetCPU,
+		TargetFeatures:       strings.Join(opts.targetFeatures, ","),
+		WholeProgram:         opts.wholeProgram,
+		EvalInit:             opts.evalInit,
+		InlineThreshold:      opts.inlineLimit,
+		FPContract:           opts.fpContract,
+		UnsafeMath:           opts.unsafeMath,
+		FiniteMath:           opts.finiteMath,
 	}
 	if opts.dumpTrace {
 		copts.Logger = log.New(os.Stderr, "", 0)
@@ -102,6 +117,10 @@ type sanitizerOptions struct {
 	address, thread, memory, dataflow bool
 }
 
+func (san *sanitizerOptions) enabled() bool {
+	return san.address || san.thread || san.memory || san.dataflow
+}
+
 func (san *sanitizerOptions) resourcePath() string {
 	return filepath.Join(san.crtPrefix, "lib"+LibDirSuffix, "clang", llvmVersion())
 }
@@ -171,17 +190,25 @@ type driverOptions struct {
 	output  string
 
 	bprefix         string
+	compressDebug   string
 	debugPrefixMaps []debug.PrefixMap
 	dumpSSA         bool
 	dumpTrace       bool
 	emitIR          bool
+	evalInit        bool
+	finiteMath      bool
+	fpContract      irgen.FPContractMode
 	gccgoPath       string
+	gdbScripts      bool
 	generateDebug   bool
 	importPaths     []string
+	inlineLimit     int
 	libPaths        []string
 	llvmArgs        []string
 	lto             bool
+	noSplitStack    bool
 	optLevel        int
+	optSiblingCalls bool
 	pic             bool
 	pieLink         bool
 	pkgpath         string
@@ -189,10 +216,61 @@ type driverOptions struct {
 	prefix          string
 	sanitizer       sanitizerOptions
 	sizeLevel       int
+	splitDwarf      bool
+	stackProtector  llvm.Attribute
 	staticLibgcc    bool
 	staticLibgo     bool
 	staticLink      bool
+	targetCPU       string
+	targetFeatures  []string
 	triple          string
+	tuneCPU         string
+	unsafeMath      bool
+	wholeProgram    bool
+}
+
+// splitDwarfFile returns the name of the file to which the debug info of
+// the object file output is to be split, or the empty string if none. The
+// file is named after the final output, as the object file is temporary
+// when compiling and linking in one step.
+func (opts *driverOptions) splitDwarfFile(kind actionKind, output string) string {
+	if !opts.splitDwarf || kind != actionCompile || opts.lto || opts.emitIR || output == "-" {
+		return ""
+	}
+	return strings.TrimSuffix(opts.output, filepath.Ext(opts.output)) + ".dwo"
+}
+
+// processDebugSections moves the split DWARF sections of the object file
+// at path to splitDwarfFile, if not empty, and compresses the remaining
+// debug sections if requested.
+func processDebugSections(opts *driverOptions, path, splitDwarfFile string) error {
+	var cmds [][]string
+	if splitDwarfFile != "" {
+		cmds = append(cmds,
+			[]string{"--extract-dwo", path, splitDwarfFile},
+			[]string{"--strip-dwo", path})
+	}
+	if opts.compressDebug != "" {
+		cmds = append(cmds, []string{"--compress-debug-sections=" + opts.compressDebug, path})
+	}
+	for _, args := range cmds {
+		cmd := exec.Command(opts.bprefix+"objcopy", args...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			os.Stderr.Write(out)
+			return err
+		}
+	}
+	return nil
+}
+
+// gdbScript returns the path of the gdb script to be referenced by
+// compiled packages, or the empty string if none.
+func (opts *driverOptions) gdbScript() string {
+	if !opts.gdbScripts || opts.prefix == "" {
+		return ""
+	}
+	return filepath.Join(opts.prefix, "share", "llgo", "llgo-gdb.py")
 }
 
 func getInstPrefix() (string, error) {
@@ -214,6 +292,8 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 	var goInputs, otherInputs []string
 	hasOtherNonFlagInputs := false
 	noPrefix := false
+	evalInit := -1
+	inlineLimit := -1
 	actionKind := actionLink
 	opts.triple = llvm.DefaultTargetTriple()
 
@@ -324,10 +404,19 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		case strings.HasPrefix(args[0], "-fgo-relative-import-path="):
 			// TODO(pcc): Handle this.
 
-		case strings.HasPrefix(args[0], "-fstack-protector"):
-			// TODO(axw) set ssp function attributes. This can be useful
-			// even for Go, if it interfaces with code written in a non-
-			// memory safe language (e.g. via cgo).
+		// Stack protectors can be useful even for Go, if it interfaces
+		// with code written in a non-memory safe language (e.g. via cgo).
+		case args[0] == "-fstack-protector":
+			opts.stackProtector = llvm.StackProtectAttribute
+
+		case args[0] == "-fstack-protector-strong":
+			opts.stackProtector = llvm.StackProtectStrongAttribute
+
+		case args[0] == "-fstack-protector-all":
+			opts.stackProtector = llvm.StackProtectReqAttribute
+
+		case args[0] == "-fno-stack-protector":
+			opts.stackProtector = 0
 
 		case strings.HasPrefix(args[0], "-W"):
 			// Go doesn't do warnings. Ignore.
@@ -339,6 +428,57 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 			opts.plugins = append(opts.plugins, args[1])
 			consumedArgs = 2
 
+		case args[0] == "-finit-eval":
+			evalInit = 1
+
+		case args[0] == "-fno-init-eval":
+			evalInit = 0
+
+		case strings.HasPrefix(args[0], "-finline-limit="):
+			inlineLimit, err = strconv.Atoi(args[0][15:])
+			if err != nil || inlineLimit < 0 {
+				return opts, fmt.Errorf("invalid argument to '-finline-limit=': '%s'", args[0][15:])
+			}
+
+		case args[0] == "-fno-inline":
+			inlineLimit = 0
+
+		case args[0] == "-ffp-contract=off":
+			opts.fpContract = irgen.FPContractOff
+
+		case args[0] == "-ffp-contract=on":
+			opts.fpContract = irgen.FPContractOn
+
+		case args[0] == "-ffp-contract=fast":
+			opts.fpContract = irgen.FPContractFast
+
+		case args[0] == "-funsafe-math-optimizations":
+			opts.unsafeMath = true
+
+		case args[0] == "-fno-unsafe-math-optimizations":
+			opts.unsafeMath = false
+
+		case args[0] == "-ffast-math":
+			opts.unsafeMath = true
+			opts.finiteMath = true
+			opts.fpContract = irgen.FPContractFast
+
+		case args[0] == "-fno-fast-math":
+			opts.unsafeMath = false
+			opts.finiteMath = false
+
+		case args[0] == "-fsplit-stack":
+			opts.noSplitStack = false
+
+		case args[0] == "-fno-split-stack":
+			opts.noSplitStack = true
+
+		case args[0] == "-foptimize-sibling-calls":
+			opts.optSiblingCalls = true
+
+		case args[0] == "-fno-optimize-sibling-calls":
+			opts.optSiblingCalls = false
+
 		case args[0] == "-fno-toplevel-reorder":
 			// This is a GCC-specific code generation option. Ignore.
 
@@ -368,9 +508,35 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		case args[0] == "-fsanitize=dataflow":
 			opts.sanitizer.dataflow = true
 
+		case args[0] == "-fwhole-program":
+			opts.wholeProgram = true
+
 		case args[0] == "-g":
 			opts.generateDebug = true
 
+		case args[0] == "-gsplit-dwarf":
+			opts.generateDebug = true
+			opts.splitDwarf = true
+
+		case args[0] == "-gz":
+			opts.compressDebug = "zlib"
+
+		case strings.HasPrefix(args[0], "-gz="):
+			switch format := args[0][4:]; format {
+			case "none":
+				opts.compressDebug = ""
+			case "zlib", "zlib-gnu":
+				opts.compressDebug = format
+			default:
+				return opts, fmt.Errorf("unsupported argument '%s' to option 'gz'", format)
+			}
+
+		case args[0] == "-fgdb-scripts":
+			opts.gdbScripts = true
+
+		case args[0] == "-fno-gdb-scripts":
+			opts.gdbScripts = false
+
 		case args[0] == "-mllvm":
 			if len(args) == 1 {
 				return opts, errors.New("missing argument after '-mllvm'")
@@ -378,9 +544,32 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 			opts.llvmArgs = append(opts.llvmArgs, args[1])
 			consumedArgs = 2
 
-		case strings.HasPrefix(args[0], "-m"), args[0] == "-funsafe-math-optimizations", args[0] == "-ffp-contract=off":
+		case strings.HasPrefix(args[0], "-march="):
+			opts.targetCPU = args[0][7:]
+
+		case strings.HasPrefix(args[0], "-mcpu="):
+			opts.targetCPU = args[0][6:]
+
+		case strings.HasPrefix(args[0], "-mtune="):
+			// The CPU name is checked, but otherwise ignored: this
+			// LLVM cannot tune for a CPU other than the target CPU.
+			opts.tuneCPU = args[0][7:]
+
+		case args[0] == "-m32", args[0] == "-m64", args[0] == "-mx32", strings.HasPrefix(args[0], "-m") && strings.Contains(args[0], "="):
 			// TODO(pcc): Handle code generation options.
 
+		case strings.HasPrefix(args[0], "-mno-"):
+			if args[0] == "-mno-" || !isTargetName(args[0][5:]) {
+				return opts, fmt.Errorf("unrecognized command line option '%s'", args[0])
+			}
+			opts.targetFeatures = append(opts.targetFeatures, "-"+args[0][5:])
+
+		case strings.HasPrefix(args[0], "-m"):
+			if args[0] == "-m" || !isTargetName(args[0][2:]) {
+				return opts, fmt.Errorf("unrecognized command line option '%s'", args[0])
+			}
+			opts.targetFeatures = append(opts.targetFeatures, "+"+args[0][2:])
+
 		case args[0] == "-no-prefix":
 			noPrefix = true
 
@@ -421,6 +610,21 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		return opts, errors.New("no input files")
 	}
 
+	// The runtime without split stacks is only built on its own, and not
+	// for LTO or any sanitizer.
+	if opts.noSplitStack {
+		switch {
+		case opts.lto:
+			return opts, errors.New("'-fno-split-stack' is not supported with '-flto'")
+		case opts.sanitizer.enabled():
+			return opts, errors.New("'-fno-split-stack' is not supported with '-fsanitize'")
+		}
+	}
+
+	if err := validateTargetOptions(&opts); err != nil {
+		return opts, err
+	}
+
 	if !noPrefix {
 		opts.prefix, err = getInstPrefix()
 		if err != nil {
@@ -428,6 +632,34 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		}
 	}
 
+	// Initializers are evaluated at compile time by default when optimizing.
+	if evalInit == -1 {
+		opts.evalInit = opts.optLevel > 0
+	} else {
+		opts.evalInit = evalInit == 1
+	}
+
+	// Small functions are inlined by default when optimizing.
+	if inlineLimit == -1 {
+		if opts.optLevel > 0 {
+			opts.inlineLimit = ssaopt.DefaultInlineThreshold
+		}
+	} else {
+		opts.inlineLimit = inlineLimit
+	}
+
+	// The split DWARF sections are emitted alongside the skeleton compile
+	// unit, and moved to the .dwo file after the object file is written.
+	if opts.splitDwarf {
+		opts.llvmArgs = append(opts.llvmArgs, "-split-dwarf=Enable")
+	}
+
+	// The runtime built for -flto is only available as an archive, whose
+	// Go code is compiled at link time along with the program.
+	if opts.lto {
+		opts.staticLibgo = true
+	}
+
 	if opts.sanitizer.crtPrefix == "" {
 		opts.sanitizer.crtPrefix = opts.prefix
 	}
@@ -474,6 +706,75 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 	return opts, nil
 }
 
+// validateTargetOptions checks that the CPUs given by the -march, -mcpu
+// and -mtune options, and the features given by the -m and -mno- options,
+// are supported by the LLVM target for the target triple.
+func validateTargetOptions(opts *driverOptions) error {
+	if opts.targetCPU == "" && opts.tuneCPU == "" && len(opts.targetFeatures) == 0 {
+		return nil
+	}
+	if _, err := llvm.GetTargetFromTriple(opts.triple); err != nil {
+		return err
+	}
+	for _, cpu := range []string{opts.targetCPU, opts.tuneCPU} {
+		if cpu == "native" {
+			return errors.New("'native' is not a supported CPU; specify the CPU explicitly")
+		}
+		if cpu != "" && (!isTargetName(cpu) || !isValidCPU(opts.triple, cpu)) {
+			return fmt.Errorf("invalid CPU name '%s' for target '%s'", cpu, opts.triple)
+		}
+	}
+	for _, feature := range opts.targetFeatures {
+		if !isValidFeature(opts.triple, opts.targetCPU, feature[1:]) {
+			flag := "-m" + feature[1:]
+			if feature[0] == '-' {
+				flag = "-mno-" + feature[1:]
+			}
+			return fmt.Errorf("unrecognized command line option '%s' for target '%s'", flag, opts.triple)
+		}
+	}
+	return nil
+}
+
+// isTargetName reports whether name is of the form of an LLVM CPU or
+// target feature name.
+func isTargetName(name string) bool {
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// createTargetMachine creates a target machine for the target triple, CPU
+// and features given by the driver options.
+func createTargetMachine(opts *driverOptions) (llvm.TargetMachine, error) {
+	target, err := llvm.GetTargetFromTriple(opts.triple)
+	if err != nil {
+		return llvm.TargetMachine{}, err
+	}
+
+	optLevel := [...]llvm.CodeGenOptLevel{
+		llvm.CodeGenLevelNone,
+		llvm.CodeGenLevelLess,
+		llvm.CodeGenLevelDefault,
+		llvm.CodeGenLevelAggressive,
+	}[opts.optLevel]
+
+	relocMode := llvm.RelocStatic
+	if opts.pic {
+		relocMode = llvm.RelocPIC
+	}
+
+	return target.CreateTargetMachine(opts.triple, opts.targetCPU,
+		strings.Join(opts.targetFeatures, ","), optLevel,
+		relocMode, llvm.CodeModelDefault), nil
+}
+
 func runPasses(opts *driverOptions, tm llvm.TargetMachine, m llvm.Module) {
 	fpm := llvm.NewFunctionPassManagerForModule(m)
 	defer fpm.Dispose()
@@ -550,6 +851,8 @@ func getLibDir(opts *driverOptions) string {
 	switch {
 	case opts.lto:
 		return filepath.Join(lib, "llvm-lto.0")
+	case opts.noSplitStack:
+		return filepath.Join(lib, "llvm-nosplit.0")
 	case opts.sanitizer.address:
 		return filepath.Join(lib, "llvm-asan.0")
 	case opts.sanitizer.thread:
@@ -586,7 +889,8 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 		}
 
 	case actionCompile, actionAssemble:
-		compiler, err := initCompiler(opts)
+		splitDwarfFile := opts.splitDwarfFile(kind, output)
+		compiler, err := initCompiler(opts, splitDwarfFile)
 		if err != nil {
 			return err
 		}
@@ -604,25 +908,10 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 
 		defer module.Dispose()
 
-		target, err := llvm.GetTargetFromTriple(opts.triple)
+		tm, err := createTargetMachine(opts)
 		if err != nil {
 			return err
 		}
-
-		optLevel := [...]llvm.CodeGenOptLevel{
-			llvm.CodeGenLevelNone,
-			llvm.CodeGenLevelLess,
-			llvm.CodeGenLevelDefault,
-			llvm.CodeGenLevelAggressive,
-		}[opts.optLevel]
-
-		relocMode := llvm.RelocStatic
-		if opts.pic {
-			relocMode = llvm.RelocPIC
-		}
-
-		tm := target.CreateTargetMachine(opts.triple, "", "", optLevel,
-			relocMode, llvm.CodeModelDefault)
 		defer tm.Dispose()
 
 		runPasses(opts, tm, module.Module)
@@ -640,9 +929,16 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 
 		switch {
 		case !opts.lto && !opts.emitIR:
+			var asm string
 			if module.ExportData != nil {
-				asm := getMetadataSectionInlineAsm(".go_export")
+				asm += getMetadataSectionInlineAsm(".go_export")
 				asm += getDataInlineAsm(module.ExportData)
+			}
+			if module.InlineData != nil {
+				asm += getMetadataSectionInlineAsm(".go_inline")
+				asm += getDataInlineAsm(module.InlineData)
+			}
+			if asm != "" {
 				module.Module.SetInlineAsm(asm)
 			}
 
@@ -657,8 +953,13 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			defer mb.Dispose()
 
 			bytes := mb.Bytes()
-			_, err = file.Write(bytes)
-			return err
+			if _, err := file.Write(bytes); err != nil {
+				return err
+			}
+			if kind == actionCompile && output != "-" {
+				return processDebugSections(opts, output, splitDwarfFile)
+			}
+			return nil
 
 		case opts.lto:
 			bcmb := llvm.WriteBitcodeToMemoryBuffer(module.Module)
@@ -673,10 +974,16 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			defer outmodule.Dispose()
 			asm := getMetadataSectionInlineAsm(".llvmbc")
 			asm += getDataInlineAsm(bcmb.Bytes())
+			asm += getMetadataSectionInlineAsm(ltoSymbolsSection)
+			asm += getDataInlineAsm(ltoSymbolTable(module.Module))
 			if module.ExportData != nil {
 				asm += getMetadataSectionInlineAsm(".go_export")
 				asm += getDataInlineAsm(module.ExportData)
 			}
+			if module.InlineData != nil {
+				asm += getMetadataSectionInlineAsm(".go_inline")
+				asm += getDataInlineAsm(module.InlineData)
+			}
 			outmodule.SetInlineAsm(asm)
 
 			fileType := llvm.AssemblyFile
@@ -706,7 +1013,17 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 		}
 
 	case actionLink:
-		// TODO(pcc): Teach this to do LTO.
+		if opts.lto {
+			ltoObject, err := linkTimeOptimize(opts, inputs)
+			if err != nil {
+				return err
+			}
+			if ltoObject != "" {
+				defer os.Remove(ltoObject)
+				inputs = append([]string{ltoObject}, inputs...)
+			}
+		}
+
 		args := []string{"-o", output}
 		if opts.pic {
 			args = append(args, "-fPIC")
@@ -720,6 +1037,9 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 		if opts.staticLibgcc {
 			args = append(args, "-static-libgcc")
 		}
+		if opts.compressDebug != "" {
+			args = append(args, "-Wl,--compress-debug-sections="+opts.compressDebug)
+		}
 		for _, p := range opts.libPaths {
 			args = append(args, "-L", p)
 		}
@@ -0,0 +1,354 @@
+//===- lto.go - link-time optimization for gllgo ---------------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements link-time optimization of the bitcode embedded in the
+// .llvmbc sections of object files compiled with -flto.
+//
+//===----------------------------------------------------------------------===//
+
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+const archiveMagic = "!<arch>\n"
+
+// readArchive returns the members of the ar archive data, less the symbol
+// table and the table of long member names.
+func readArchive(data []byte) ([][]byte, error) {
+	data = data[len(archiveMagic):]
+	var members [][]byte
+	for len(data) >= 60 {
+		name := strings.TrimSpace(string(data[0:16]))
+		size, err := strconv.ParseInt(strings.TrimSpace(string(data[48:58])), 10, 64)
+		if err != nil || size < 0 || size > int64(len(data)-60) {
+			return nil, errors.New("malformed archive")
+		}
+		member := data[60 : 60+size]
+		data = data[60+size:]
+		// Members are aligned to even offsets.
+		if size%2 == 1 && len(data) > 0 {
+			data = data[1:]
+		}
+
+		switch {
+		case name == "/", name == "//", name == "/SYM64/":
+			continue
+		case strings.HasPrefix(name, "#1/"):
+			// BSD archives store long names before the member.
+			n, err := strconv.Atoi(name[3:])
+			if err != nil || n > len(member) {
+				return nil, errors.New("malformed archive")
+			}
+			member = member[n:]
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// ltoSymbolsSection is the name of the section of an object file compiled
+// with -flto that lists the symbols defined and referred to by its bitcode,
+// so that archive members may be resolved without parsing the bitcode.
+const ltoSymbolsSection = ".llgo_lto_symbols"
+
+// ltoSymbolTable returns the contents of the ltoSymbolsSection for m: a
+// line for each symbol, "D name" for those defined by m and "U name" for
+// those it refers to.
+func ltoSymbolTable(m llvm.Module) []byte {
+	var b bytes.Buffer
+	add := func(v llvm.Value) {
+		name := v.Name()
+		switch {
+		case name == "" || strings.HasPrefix(name, "llvm."):
+		case v.IsDeclaration(), v.Linkage() == llvm.AvailableExternallyLinkage:
+			// An available_externally definition may be discarded,
+			// so the symbol must still be defined elsewhere.
+			b.WriteString("U " + name + "\n")
+		case v.Linkage() != llvm.InternalLinkage && v.Linkage() != llvm.PrivateLinkage:
+			b.WriteString("D " + name + "\n")
+		}
+	}
+	for fn := m.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		add(fn)
+	}
+	for g := m.FirstGlobal(); !g.IsNil(); g = llvm.NextGlobal(g) {
+		add(g)
+	}
+	return b.Bytes()
+}
+
+// An ltoObject is an object file among the link inputs, which holds either
+// native code or, if it was compiled with -flto, bitcode.
+type ltoObject struct {
+	bitcode   []byte
+	defined   []string
+	undefined []string
+}
+
+// readObject reads the object file data, returning nil if it is not an
+// ELF object file.
+func readObject(data []byte) (*ltoObject, error) {
+	ef, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+	var obj ltoObject
+	if sec := ef.Section(".llvmbc"); sec != nil {
+		obj.bitcode, err = sec.Data()
+		if err != nil {
+			return nil, err
+		}
+		symsec := ef.Section(ltoSymbolsSection)
+		if symsec == nil {
+			return nil, errors.New("object file compiled with -flto has no symbol table")
+		}
+		syms, err := symsec.Data()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(syms), "\n") {
+			switch {
+			case strings.HasPrefix(line, "D "):
+				obj.defined = append(obj.defined, line[2:])
+			case strings.HasPrefix(line, "U "):
+				obj.undefined = append(obj.undefined, line[2:])
+			}
+		}
+		return &obj, nil
+	}
+	syms, _ := ef.Symbols()
+	for _, sym := range syms {
+		switch {
+		case sym.Name == "":
+		case sym.Section == elf.SHN_UNDEF:
+			obj.undefined = append(obj.undefined, sym.Name)
+		case elf.ST_BIND(sym.Info) != elf.STB_LOCAL:
+			obj.defined = append(obj.defined, sym.Name)
+		}
+	}
+	return &obj, nil
+}
+
+// readInput reads the object file or archive at path, and returns the
+// object files it contains. Inputs of other kinds are left to the linker.
+func readInput(path string) (objects []*ltoObject, isArchive bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	members := [][]byte{data}
+	isArchive = bytes.HasPrefix(data, []byte(archiveMagic))
+	if isArchive {
+		members, err = readArchive(data)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	for _, member := range members {
+		obj, err := readObject(member)
+		if err != nil {
+			return nil, false, err
+		}
+		if obj != nil {
+			objects = append(objects, obj)
+		}
+	}
+	return objects, isArchive, nil
+}
+
+// ltoInputs returns the paths of the object files and archives that may
+// contain bitcode: those among the link inputs, and the libgo archives.
+func ltoInputs(opts *driverOptions, inputs []string) []string {
+	var paths []string
+	for _, input := range inputs {
+		if ext := filepath.Ext(input); !strings.HasPrefix(input, "-") && (ext == ".o" || ext == ".a") {
+			paths = append(paths, input)
+		}
+	}
+	if opts.gccgoPath == "" && opts.prefix != "" {
+		libdir := filepath.Join(opts.prefix, getLibDir(opts))
+		for _, lib := range []string{"libgobegin-llgo.a", "libgo-llgo.a"} {
+			path := filepath.Join(libdir, lib)
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// resolveObjects determines the object files that are linked, as the linker
+// would: every object file given as an input, and each archive member that
+// defines a symbol referred to by another linked object file. Archives are
+// searched repeatedly until no more members are pulled in, as if grouped.
+// The native objects' references to symbols are added to preserved.
+func resolveObjects(paths []string, preserved map[string]bool) ([]*ltoObject, error) {
+	var linked, candidates []*ltoObject
+	defined := make(map[string]bool)
+	undefined := make(map[string]bool)
+	link := func(obj *ltoObject) {
+		linked = append(linked, obj)
+		for _, name := range obj.defined {
+			defined[name] = true
+			delete(undefined, name)
+		}
+		for _, name := range obj.undefined {
+			if !defined[name] {
+				undefined[name] = true
+			}
+			if obj.bitcode == nil {
+				preserved[name] = true
+			}
+		}
+	}
+
+	// The C runtime's startup code refers to main.
+	undefined["main"] = true
+	for _, path := range paths {
+		objects, isArchive, err := readInput(path)
+		if err != nil {
+			return nil, err
+		}
+		if isArchive {
+			candidates = append(candidates, objects...)
+			continue
+		}
+		for _, obj := range objects {
+			link(obj)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i, obj := range candidates {
+			if obj == nil {
+				continue
+			}
+			for _, name := range obj.defined {
+				if undefined[name] {
+					link(obj)
+					candidates[i] = nil
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return linked, nil
+}
+
+func parseBitcode(data []byte) (llvm.Module, error) {
+	mb := llvm.NewMemoryBufferFromRangeCopy(data)
+	defer mb.Dispose()
+	return llvm.ParseBitcode(mb)
+}
+
+// internalize gives the definition v internal linkage, unless it is in
+// preserved or is placed in a named section, which may be looked up by
+// other tools.
+func internalize(v llvm.Value, preserved map[string]bool) {
+	if v.IsDeclaration() || preserved[v.Name()] || v.Section() != "" {
+		return
+	}
+	switch v.Linkage() {
+	case llvm.ExternalLinkage, llvm.LinkOnceAnyLinkage, llvm.LinkOnceODRLinkage,
+		llvm.WeakAnyLinkage, llvm.WeakODRLinkage, llvm.CommonLinkage:
+		v.SetLinkage(llvm.InternalLinkage)
+	}
+}
+
+// linkTimeOptimize links the bitcode of the object files that the linker
+// would link into a single module, optimizes it and compiles it to an
+// object file, whose path is returned. Symbols not referred to by native
+// code are internalized before optimization, so that unused code may be
+// discarded. If no linked object file contains bitcode, linkTimeOptimize
+// returns the empty string.
+func linkTimeOptimize(opts *driverOptions, inputs []string) (string, error) {
+	// The entry points called by libgobegin are preserved even if it is
+	// not found among the inputs.
+	preserved := map[string]bool{
+		"main":           true,
+		"main.main":      true,
+		"__go_init_main": true,
+	}
+	objects, err := resolveObjects(ltoInputs(opts, inputs), preserved)
+	if err != nil {
+		return "", err
+	}
+	var bitcode [][]byte
+	for _, obj := range objects {
+		if obj.bitcode != nil {
+			bitcode = append(bitcode, obj.bitcode)
+		}
+	}
+	if len(bitcode) == 0 {
+		return "", nil
+	}
+
+	module, err := parseBitcode(bitcode[0])
+	if err != nil {
+		return "", err
+	}
+	defer module.Dispose()
+	for _, data := range bitcode[1:] {
+		m, err := parseBitcode(data)
+		if err != nil {
+			return "", err
+		}
+		if err := llvm.LinkModules(module, m); err != nil {
+			return "", err
+		}
+	}
+
+	for fn := module.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		internalize(fn, preserved)
+	}
+	for g := module.FirstGlobal(); !g.IsNil(); g = llvm.NextGlobal(g) {
+		internalize(g, preserved)
+	}
+
+	tm, err := createTargetMachine(opts)
+	if err != nil {
+		return "", err
+	}
+	defer tm.Dispose()
+
+	runPasses(opts, tm, module)
+
+	mb, err := tm.EmitToMemoryBuffer(module, llvm.ObjectFile)
+	if err != nil {
+		return "", err
+	}
+	defer mb.Dispose()
+
+	tmpfile, err := ioutil.TempFile("", "llgo-lto")
+	if err != nil {
+		return "", err
+	}
+	tmpfile.Close()
+	if err := os.Remove(tmpfile.Name()); err != nil {
+		return "", err
+	}
+	output := tmpfile.Name() + ".o"
+	if err := ioutil.WriteFile(output, mb.Bytes(), 0666); err != nil {
+		return "", err
+	}
+	return output, nil
+}
@@ -0,0 +1,65 @@
+//===- target.cpp - target CPU and feature validation ----------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file defines the C functions used by target.go to look up CPU and
+// target feature names in the subtarget tables of an LLVM target.
+//
+//===----------------------------------------------------------------------===//
+
+#include "llvm/MC/MCSubtargetInfo.h"
+#include "llvm/Support/TargetRegistry.h"
+#include <cstring>
+#include <memory>
+#include <string>
+
+using namespace llvm;
+
+static MCSubtargetInfo *createSubtargetInfo(const char *triple,
+                                            const char *cpu) {
+  std::string err;
+  const Target *T = TargetRegistry::lookupTarget(triple, err);
+  if (!T)
+    return nullptr;
+  return T->createMCSubtargetInfo(triple, cpu, "");
+}
+
+extern "C" int llgoIsValidCPU(const char *triple, const char *cpu) {
+  std::unique_ptr<MCSubtargetInfo> STI(createSubtargetInfo(triple, ""));
+  return STI && STI->isCPUStringValid(cpu);
+}
+
+// MCSubtargetInfo does not expose its feature table, and looking up an
+// unknown feature through it prints a warning. Explicit instantiations are
+// exempt from access checking, which lets getProcFeatures name the private
+// member holding the table.
+namespace {
+
+struct ProcFeatures {
+  typedef ArrayRef<SubtargetFeatureKV> MCSubtargetInfo::*type;
+  friend type getProcFeatures(ProcFeatures);
+};
+
+template <typename Tag, typename Tag::type Member> struct AccessMember {
+  friend typename Tag::type getProcFeatures(Tag) { return Member; }
+};
+
+template struct AccessMember<ProcFeatures, &MCSubtargetInfo::ProcFeatures>;
+
+} // end anonymous namespace
+
+extern "C" int llgoIsValidFeature(const char *triple, const char *cpu,
+                                  const char *feature) {
+  std::unique_ptr<MCSubtargetInfo> STI(createSubtargetInfo(triple, cpu));
+  if (!STI)
+    return 0;
+  for (const SubtargetFeatureKV &KV : (*STI).*getProcFeatures(ProcFeatures()))
+    if (std::strcmp(KV.Key, feature) == 0)
+      return 1;
+  return 0;
+}
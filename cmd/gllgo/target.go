@@ -0,0 +1,49 @@
+//===- target.go - target CPU and feature validation -----------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file checks CPU and target feature names against the tables of the
+// LLVM target, which the LLVM C API does not expose.
+//
+//===----------------------------------------------------------------------===//
+
+package main
+
+/*
+#include <stdlib.h>
+
+int llgoIsValidCPU(const char *triple, const char *cpu);
+int llgoIsValidFeature(const char *triple, const char *cpu, const char *feature);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// isValidCPU reports whether cpu is the name of a CPU supported by the
+// LLVM target for triple.
+func isValidCPU(triple, cpu string) bool {
+	ctriple := C.CString(triple)
+	defer C.free(unsafe.Pointer(ctriple))
+	ccpu := C.CString(cpu)
+	defer C.free(unsafe.Pointer(ccpu))
+	return C.llgoIsValidCPU(ctriple, ccpu) != 0
+}
+
+// isValidFeature reports whether feature is the name of a target feature
+// supported by the LLVM target for triple, when generating code for cpu.
+func isValidFeature(triple, cpu, feature string) bool {
+	ctriple := C.CString(triple)
+	defer C.free(unsafe.Pointer(ctriple))
+	ccpu := C.CString(cpu)
+	defer C.free(unsafe.Pointer(ccpu))
+	cfeature := C.CString(feature)
+	defer C.free(unsafe.Pointer(cfeature))
+	return C.llgoIsValidFeature(ctriple, ccpu, cfeature) != 0
+}
@@ -0,0 +1,61 @@
+//===- llgo-symbolize.go - demangle function names in llgo output ---------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This is llgo-symbolize, which replaces the mangled function names in the
+// crash output of llgo programs, or the output of tools such as perf script,
+// with their Go forms.
+//
+//===----------------------------------------------------------------------===//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"llvm.org/llgo/symbolize"
+)
+
+var goroutine = flag.Int("goroutine", -1, "only print the stack of the goroutine with this ID")
+
+func symbolizeFile(path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return symbolize.Symbolize(os.Stdout, r, *goroutine)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llgo-symbolize [-goroutine id] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	status := 0
+	for _, path := range paths {
+		if err := symbolizeFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "llgo-symbolize: %v\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,27 @@
+//===- comdat.cpp - comdat groups for debug sections -----------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file defines the C function used by comdat.go to place a global in a
+// comdat group.
+//
+//===----------------------------------------------------------------------===//
+
+#include "llvm-c/Core.h"
+#include "llvm/IR/Comdat.h"
+#include "llvm/IR/GlobalObject.h"
+#include "llvm/IR/Module.h"
+
+using namespace llvm;
+
+extern "C" void llgoSetComdat(LLVMValueRef global) {
+  GlobalObject *GO = unwrap<GlobalObject>(global);
+  Comdat *C = GO->getParent()->getOrInsertComdat(GO->getName());
+  C->setSelectionKind(Comdat::Any);
+  GO->setComdat(C);
+}
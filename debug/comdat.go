@@ -0,0 +1,34 @@
+//===- comdat.go - comdat groups for debug sections ------------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements the placement of globals in comdat groups, which the
+// LLVM C API does not expose.
+//
+//===----------------------------------------------------------------------===//
+
+package debug
+
+/*
+#include "llvm-c/Core.h"
+
+void llgoSetComdat(LLVMValueRef global);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// setComdat places global in a comdat group of its own name, so that the
+// linker keeps a single copy of the section holding it.
+func setComdat(global llvm.Value) {
+	C.llgoSetComdat(C.LLVMValueRef(unsafe.Pointer(global.C)))
+}
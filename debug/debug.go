@@ -20,7 +20,7 @@ import (
 	"os"
 	"strings"
 
-	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gc/go/ast"
 	"llvm.org/llgo/third_party/gotools/go/types"
 	"llvm.org/llgo/third_party/gotools/go/types/typeutil"
 
@@ -31,8 +31,72 @@ const (
 	// non-standard debug metadata tags
 	tagAutoVariable dwarf.Tag = 0x100
 	tagArgVariable  dwarf.Tag = 0x101
+
+	// DWARF expression operations
+	dwarfOpDeref = 0x06
+	dwarfOpPlus  = 0x22
 )
 
+// Types modelling the libgo runtime structures referred to by maps,
+// channels and interfaces, so that debuggers and pretty-printers may
+// inspect their contents.
+var (
+	typeDescriptorType = types.NewNamed(types.NewTypeName(0, nil, "__go_type_descriptor", nil), nil, nil)
+	uncommonType       = runtimeStruct("__go_uncommon_type",
+		field("__name", types.NewPointer(types.Typ[types.String])),
+		field("__pkg_path", types.NewPointer(types.Typ[types.String])),
+	)
+	mapDescriptorType = runtimeStruct("__go_map_descriptor",
+		field("__map_descriptor", types.NewPointer(typeDescriptorType)),
+		field("__entry_size", types.Typ[types.Uintptr]),
+		field("__key_offset", types.Typ[types.Uintptr]),
+		field("__val_offset", types.Typ[types.Uintptr]),
+	)
+	waitQType = types.NewStruct([]*types.Var{
+		field("first", types.Typ[types.UnsafePointer]),
+		field("last", types.Typ[types.UnsafePointer]),
+	}, nil)
+	channelType = runtimeStruct("__go_channel",
+		field("qcount", types.Typ[types.Uint]),
+		field("dataqsiz", types.Typ[types.Uint]),
+		field("elemsize", types.Typ[types.Uint16]),
+		field("pad", types.Typ[types.Uint16]),
+		field("closed", types.Typ[types.Bool]),
+		field("elemtype", types.NewPointer(typeDescriptorType)),
+		field("sendx", types.Typ[types.Uint]),
+		field("recvx", types.Typ[types.Uint]),
+		field("recvq", waitQType),
+		field("sendq", waitQType),
+		field("lock", types.Typ[types.Uintptr]),
+	)
+)
+
+func init() {
+	typeDescriptorType.SetUnderlying(types.NewStruct([]*types.Var{
+		field("__code", types.Typ[types.Uint8]),
+		field("__align", types.Typ[types.Uint8]),
+		field("__field_align", types.Typ[types.Uint8]),
+		field("__size", types.Typ[types.Uintptr]),
+		field("__hash", types.Typ[types.Uint32]),
+		field("__hashfn", types.Typ[types.UnsafePointer]),
+		field("__equalfn", types.Typ[types.UnsafePointer]),
+		field("__gc", types.Typ[types.UnsafePointer]),
+		field("__reflection", types.NewPointer(types.Typ[types.String])),
+		field("__uncommon", types.NewPointer(uncommonType)),
+		field("__pointer_to_this", types.NewPointer(typeDescriptorType)),
+		field("__zero", types.Typ[types.UnsafePointer]),
+	}, nil))
+}
+
+func runtimeStruct(name string, fields ...*types.Var) *types.Named {
+	obj := types.NewTypeName(0, nil, name, nil)
+	return types.NewNamed(obj, types.NewStruct(fields, nil), nil)
+}
+
+func field(name string, t types.Type) *types.Var {
+	return types.NewVar(0, nil, name, t)
+}
+
 type PrefixMap struct {
 	Source, Replacement string
 }
@@ -45,15 +109,31 @@ type DIBuilder struct {
 	files      map[*token.File]llvm.Metadata
 	cu, fn, lb llvm.Metadata
 	fnFile     string
+	scopes     map[ast.Node]*types.Scope
+	scopeNodes map[*types.Scope]ast.Node
+	blocks     []lexicalBlock
+	blockIndex map[*types.Scope]int
+	vars       map[*types.Var]llvm.Metadata
 	sizes      types.Sizes
 	fset       *token.FileSet
 	prefixMaps []PrefixMap
 	types      typeutil.Map
 	voidType   llvm.Metadata
+	byteType   llvm.Metadata
+	runeType   llvm.Metadata
+}
+
+// lexicalBlock describes a scope within the current function.
+type lexicalBlock struct {
+	pos, end token.Pos
+	parent   int // index into DIBuilder.blocks, or -1 for the function
+	md       llvm.Metadata
 }
 
-// NewDIBuilder creates a new debug information builder.
-func NewDIBuilder(sizes types.Sizes, module llvm.Module, fset *token.FileSet, prefixMaps []PrefixMap) *DIBuilder {
+// NewDIBuilder creates a new debug information builder. If splitName is
+// not empty, it is the name of the file to which the debug info is to be
+// split, as recorded in the skeleton compile unit.
+func NewDIBuilder(sizes types.Sizes, module llvm.Module, fset *token.FileSet, prefixMaps []PrefixMap, splitName string) *DIBuilder {
 	var d DIBuilder
 	d.module = module
 	d.files = make(map[*token.File]llvm.Metadata)
@@ -61,7 +141,7 @@ func NewDIBuilder(sizes types.Sizes, module llvm.Module, fset *token.FileSet, pr
 	d.fset = fset
 	d.prefixMaps = prefixMaps
 	d.builder = llvm.NewDIBuilder(d.module)
-	d.cu = d.createCompileUnit()
+	d.cu = d.createCompileUnit(splitName)
 	return &d
 }
 
@@ -98,10 +178,19 @@ func (d *DIBuilder) getFile(file *token.File) llvm.Metadata {
 	return diFile
 }
 
+// position returns debug metadata for the file containing pos, and the
+// line of pos, or zero values if pos is not known.
+func (d *DIBuilder) position(pos token.Pos) (llvm.Metadata, int) {
+	if file := d.fset.File(pos); file != nil {
+		return d.getFile(file), file.Line(pos)
+	}
+	return llvm.Metadata{}, 0
+}
+
 // createCompileUnit creates and returns debug metadata for the compile
 // unit as a whole, using the first file in the file set as a representative
 // (the choice of file is arbitrary).
-func (d *DIBuilder) createCompileUnit() llvm.Metadata {
+func (d *DIBuilder) createCompileUnit(splitName string) llvm.Metadata {
 	var file *token.File
 	d.fset.Iterate(func(f *token.File) bool {
 		file = f
@@ -112,16 +201,19 @@ func (d *DIBuilder) createCompileUnit() llvm.Metadata {
 		panic("could not get current directory: " + err.Error())
 	}
 	return d.builder.CreateCompileUnit(llvm.DICompileUnit{
-		Language: llvm.DW_LANG_Go,
-		File:     d.remapFilePath(file.Name()),
-		Dir:      dir,
-		Producer: "llgo",
+		Language:           llvm.DW_LANG_Go,
+		File:               d.remapFilePath(file.Name()),
+		Dir:                dir,
+		Producer:           "llgo",
+		SplitDebugFilename: splitName,
 	})
 }
 
 // PushFunction creates debug metadata for the specified function,
-// and pushes it onto the scope stack.
-func (d *DIBuilder) PushFunction(fnptr llvm.Value, sig *types.Signature, pos token.Pos) {
+// named name, and pushes it onto the scope stack. If syntax is the
+// declaration of the function, lexical blocks are created for the scopes
+// within the function body.
+func (d *DIBuilder) PushFunction(fnptr llvm.Value, name string, sig *types.Signature, pos token.Pos, syntax ast.Node) {
 	var diFile llvm.Metadata
 	var line int
 	if file := d.fset.File(pos); file != nil {
@@ -130,7 +222,7 @@ func (d *DIBuilder) PushFunction(fnptr llvm.Value, sig *types.Signature, pos tok
 		line = file.Line(pos)
 	}
 	d.fn = d.builder.CreateFunction(d.scope(), llvm.DIFunction{
-		Name:         fnptr.Name(), // TODO(axw) unmangled name?
+		Name:         name,
 		LinkageName:  fnptr.Name(),
 		File:         diFile,
 		Line:         line,
@@ -138,6 +230,8 @@ func (d *DIBuilder) PushFunction(fnptr llvm.Value, sig *types.Signature, pos tok
 		IsDefinition: true,
 	})
 	fnptr.SetSubprogram(d.fn)
+	d.vars = make(map[*types.Var]llvm.Metadata)
+	d.collectBlocks(syntax)
 }
 
 // PopFunction pops the previously pushed function off the scope stack.
@@ -145,16 +239,205 @@ func (d *DIBuilder) PopFunction() {
 	d.lb = llvm.Metadata{}
 	d.fn = llvm.Metadata{}
 	d.fnFile = ""
+	d.blocks = nil
+	d.blockIndex = nil
+	d.vars = nil
+}
+
+// SetScopes records the scopes of the package's syntax, as recorded by
+// the type checker, from which lexical blocks are created.
+func (d *DIBuilder) SetScopes(scopes map[ast.Node]*types.Scope) {
+	d.scopes = scopes
+	d.scopeNodes = make(map[*types.Scope]ast.Node, len(scopes))
+	for node, scope := range scopes {
+		d.scopeNodes[scope] = node
+	}
+}
+
+// collectBlocks records the scopes nested within the scope of the
+// function declared by syntax, in preorder. The function body shares the
+// scope of the function, and function literals are described when their
+// own functions are pushed.
+func (d *DIBuilder) collectBlocks(syntax ast.Node) {
+	var ftyp *ast.FuncType
+	switch syntax := syntax.(type) {
+	case *ast.FuncDecl:
+		ftyp = syntax.Type
+	case *ast.FuncLit:
+		ftyp = syntax.Type
+	}
+	if ftyp == nil {
+		return
+	}
+	if scope := d.scopes[ftyp]; scope != nil {
+		d.blockIndex = make(map[*types.Scope]int)
+		d.addBlocks(scope, -1)
+	}
+}
+
+func (d *DIBuilder) addBlocks(scope *types.Scope, parent int) {
+	for i := 0; i < scope.NumChildren(); i++ {
+		child := scope.Child(i)
+		node := d.scopeNodes[child]
+		if _, ok := node.(*ast.FuncType); ok || node == nil {
+			continue
+		}
+		d.blocks = append(d.blocks, lexicalBlock{
+			pos:    node.Pos(),
+			end:    node.End(),
+			parent: parent,
+		})
+		d.blockIndex[child] = len(d.blocks) - 1
+		d.addBlocks(child, len(d.blocks)-1)
+	}
+}
+
+// lexicalBlock returns debug metadata for the innermost lexical block
+// of the current function containing pos, creating it if necessary. If
+// pos is not within any block, lexicalBlock returns the function.
+func (d *DIBuilder) lexicalBlock(pos token.Pos) llvm.Metadata {
+	// Blocks are recorded in preorder, so the last block
+	// containing pos is the innermost.
+	index := -1
+	for i, b := range d.blocks {
+		if b.pos <= pos && pos < b.end {
+			index = i
+		}
+	}
+	return d.blockMetadata(index)
+}
+
+func (d *DIBuilder) blockMetadata(index int) llvm.Metadata {
+	if index < 0 {
+		return d.fn
+	}
+	b := &d.blocks[index]
+	if b.md.C == nil {
+		parent := d.blockMetadata(b.parent)
+		position := d.fset.Position(b.pos)
+		b.md = d.builder.CreateLexicalBlock(parent, llvm.DILexicalBlock{
+			File:   d.getFile(d.fset.File(b.pos)),
+			Line:   position.Line,
+			Column: position.Column,
+		})
+	}
+	return b.md
+}
+
+// localVariable returns debug metadata for the specified function
+// parameter or local variable, creating it if necessary. paramIndex
+// is the index of the parameter in the function's parameter list, or
+// -1 if v is not a parameter.
+func (d *DIBuilder) localVariable(v *types.Var, paramIndex int) llvm.Metadata {
+	if md := d.vars[v]; md.C != nil {
+		return md
+	}
+	tag := tagAutoVariable
+	if paramIndex >= 0 {
+		tag = tagArgVariable
+	}
+	scope := d.fn
+	if index, ok := d.blockIndex[v.Parent()]; ok {
+		scope = d.blockMetadata(index)
+	}
+	diFile, line := d.position(v.Pos())
+	md := d.builder.CreateLocalVariable(scope, llvm.DILocalVariable{
+		Tag:   tag,
+		Name:  v.Name(),
+		File:  diFile,
+		Line:  line,
+		Type:  d.DIType(v.Type()),
+		ArgNo: paramIndex + 1,
+	})
+	d.vars[v] = md
+	return md
+}
+
+// Declare creates an llvm.dbg.declare call for the specified function
+// parameter or local variable, whose storage is the alloca llv.
+func (d *DIBuilder) Declare(b llvm.Builder, v *types.Var, llv llvm.Value, paramIndex int) {
+	md := d.localVariable(v, paramIndex)
+	expr := d.builder.CreateExpression(nil)
+	call := d.builder.InsertDeclareAtEnd(llv, md, expr, b.GetInsertBlock())
+	b.SetInstDebugLocation(call)
 }
 
 // Value creates an llvm.dbg.value call for the specified register value.
-func (d *DIBuilder) Value(b llvm.Builder, v ssa.Value, llv llvm.Value, paramIndex int) {
-	// TODO(axw)
+// If indirect is true, llv is the address of the variable rather than
+// its value.
+func (d *DIBuilder) Value(b llvm.Builder, v *types.Var, llv llvm.Value, paramIndex int, indirect bool) {
+	md := d.localVariable(v, paramIndex)
+	var addr []int64
+	if indirect {
+		addr = []int64{dwarfOpDeref}
+	}
+	expr := d.builder.CreateExpression(addr)
+	call := d.builder.InsertValueAtEnd(llv, md, expr, 0, b.GetInsertBlock())
+	b.SetInstDebugLocation(call)
+}
+
+// CapturedVariable creates an llvm.dbg.value call for the variable v
+// captured by a closure, whose context is pointed to by chain. The
+// captured value is stored at offset within the context; if byRef is
+// true, the value is the address of the variable rather than its value.
+func (d *DIBuilder) CapturedVariable(b llvm.Builder, v *types.Var, chain llvm.Value, offset uint64, byRef bool) {
+	md := d.localVariable(v, -1)
+	// As in Value, a final deref describes the variable as being at the
+	// address computed by the rest of the expression: chain+offset holds
+	// the variable itself, or a pointer to it that must be loaded first.
+	addr := []int64{dwarfOpPlus, int64(offset), dwarfOpDeref}
+	if byRef {
+		addr = append(addr, dwarfOpDeref)
+	}
+	expr := d.builder.CreateExpression(addr)
+	call := d.builder.InsertValueAtEnd(chain, md, expr, 0, b.GetInsertBlock())
+	b.SetInstDebugLocation(call)
+}
+
+// GlobalVariable creates debug metadata for the global variable global,
+// of type t, declared at pos. name is the package-qualified name of the
+// variable, and local reports whether the variable is not exported.
+func (d *DIBuilder) GlobalVariable(global llvm.Value, name string, pos token.Pos, t types.Type, local bool) {
+	diFile, line := d.position(pos)
+	d.builder.CreateGlobalVariable(d.cu, llvm.DIGlobalVariable{
+		Name:        name,
+		LinkageName: global.Name(),
+		File:        diFile,
+		Line:        line,
+		Type:        d.DIType(t),
+		LocalToUnit: local,
+		Value:       global,
+	})
+}
+
+// EmbedGdbScript adds a reference to the Python script at path to the
+// .debug_gdb_scripts section, so that gdb loads the script automatically
+// when debugging a program containing the module.
+func (d *DIBuilder) EmbedGdbScript(path string) {
+	// Each entry in the section is a byte identifying the kind of
+	// entry (1 for a Python script file), followed by the file name.
+	init := llvm.ConstString("\x01"+path, true)
+	global := llvm.AddGlobal(d.module, init.Type(), "__llgo_gdb_script")
+	global.SetInitializer(init)
+	global.SetGlobalConstant(true)
+	// Each package may refer to the script, so allow duplicates, and
+	// place the entry in a comdat group so that the linker keeps only
+	// one of them in the section.
+	global.SetLinkage(llvm.WeakODRLinkage)
+	global.SetSection(".debug_gdb_scripts")
+	global.SetAlignment(1)
+	setComdat(global)
 }
 
 // SetLocation sets the current debug location.
 func (d *DIBuilder) SetLocation(b llvm.Builder, pos token.Pos) {
 	if !pos.IsValid() {
+		// Code without a position, such as that of synthetic functions,
+		// is attributed to line 0 of the function, so that calls in it
+		// may be given inlined-at locations when inlined.
+		if b.GetCurrentDebugLocation().Scope.C == nil && d.fn.C != nil {
+			b.SetCurrentDebugLocation(0, 0, d.fn, llvm.Metadata{})
+		}
 		return
 	}
 	position := d.fset.Position(pos)
@@ -163,6 +446,8 @@ func (d *DIBuilder) SetLocation(b llvm.Builder, pos token.Pos) {
 		// This can happen rarely, e.g. in init functions.
 		diFile := d.builder.CreateFile(d.remapFilePath(position.Filename), "")
 		d.lb = d.builder.CreateLexicalBlockFile(d.scope(), diFile, 0)
+	} else if len(d.blocks) > 0 {
+		d.lb = d.lexicalBlock(pos)
 	}
 	b.SetCurrentDebugLocation(uint(position.Line), uint(position.Column), d.scope(), llvm.Metadata{})
 }
@@ -206,6 +491,20 @@ func (d *DIBuilder) typeDebugDescriptor(t types.Type, name string) llvm.Metadata
 		}
 		return d.voidType
 	}
+	// byte and rune are identical to uint8 and int32, so cannot be
+	// cached in d.types, but are described as characters.
+	switch t {
+	case types.UniverseByte:
+		if d.byteType.C == nil {
+			d.byteType = d.descriptorBasic(types.UniverseByte, name)
+		}
+		return d.byteType
+	case types.UniverseRune:
+		if d.runeType.C == nil {
+			d.runeType = d.descriptorBasic(types.UniverseRune, name)
+		}
+		return d.runeType
+	}
 	if dt, ok := d.types.At(t).(llvm.Metadata); ok {
 		return dt
 	}
@@ -221,7 +520,7 @@ func (d *DIBuilder) descriptor(t types.Type, name string) llvm.Metadata {
 	case *types.Pointer:
 		return d.descriptorPointer(t)
 	case *types.Struct:
-		return d.descriptorStruct(t, name)
+		return d.descriptorStruct(t, name, token.NoPos)
 	case *types.Named:
 		return d.descriptorNamed(t)
 	case *types.Array:
@@ -260,6 +559,10 @@ func (d *DIBuilder) descriptorBasic(t *types.Basic, name string) llvm.Metadata {
 			AlignInBits: uint64(d.sizes.Alignof(t) * 8),
 		}
 		switch bi := t.Info(); {
+		case t == types.UniverseByte:
+			bt.Encoding = llvm.DW_ATE_unsigned_char
+		case t == types.UniverseRune:
+			bt.Encoding = llvm.DW_ATE_UTF
 		case bi&types.IsBoolean != 0:
 			bt.Encoding = llvm.DW_ATE_boolean
 		case bi&types.IsUnsigned != 0:
@@ -269,9 +572,7 @@ func (d *DIBuilder) descriptorBasic(t *types.Basic, name string) llvm.Metadata {
 		case bi&types.IsFloat != 0:
 			bt.Encoding = llvm.DW_ATE_float
 		case bi&types.IsComplex != 0:
-			bt.Encoding = llvm.DW_ATE_imaginary_float
-		case bi&types.IsUnsigned != 0:
-			bt.Encoding = llvm.DW_ATE_unsigned
+			bt.Encoding = llvm.DW_ATE_complex_float
 		default:
 			panic(fmt.Sprintf("unhandled: %#v", t))
 		}
@@ -287,7 +588,9 @@ func (d *DIBuilder) descriptorPointer(t *types.Pointer) llvm.Metadata {
 	})
 }
 
-func (d *DIBuilder) descriptorStruct(t *types.Struct, name string) llvm.Metadata {
+// descriptorStruct describes the struct type t, defined at pos if the
+// struct is the underlying type of a named type.
+func (d *DIBuilder) descriptorStruct(t *types.Struct, name string, pos token.Pos) llvm.Metadata {
 	fields := make([]*types.Var, t.NumFields())
 	for i := range fields {
 		fields[i] = t.Field(i)
@@ -295,19 +598,23 @@ func (d *DIBuilder) descriptorStruct(t *types.Struct, name string) llvm.Metadata
 	offsets := d.sizes.Offsetsof(fields)
 	members := make([]llvm.Metadata, len(fields))
 	for i, f := range fields {
-		// TODO(axw) file/line where member is defined.
 		t := f.Type()
+		diFile, line := d.position(f.Pos())
 		members[i] = d.builder.CreateMemberType(d.cu, llvm.DIMemberType{
 			Name:         f.Name(),
+			File:         diFile,
+			Line:         line,
 			Type:         d.DIType(t),
 			SizeInBits:   uint64(d.sizes.Sizeof(t) * 8),
 			AlignInBits:  uint64(d.sizes.Alignof(t) * 8),
 			OffsetInBits: uint64(offsets[i] * 8),
 		})
 	}
-	// TODO(axw) file/line where struct is defined.
+	diFile, line := d.position(pos)
 	return d.builder.CreateStructType(d.cu, llvm.DIStructType{
 		Name:        name,
+		File:        diFile,
+		Line:        line,
 		SizeInBits:  uint64(d.sizes.Sizeof(t) * 8),
 		AlignInBits: uint64(d.sizes.Alignof(t) * 8),
 		Elements:    members,
@@ -315,12 +622,7 @@ func (d *DIBuilder) descriptorStruct(t *types.Struct, name string) llvm.Metadata
 }
 
 func (d *DIBuilder) descriptorNamed(t *types.Named) llvm.Metadata {
-	var diFile llvm.Metadata
-	var line int
-	if file := d.fset.File(t.Obj().Pos()); file != nil {
-		line = file.Line(t.Obj().Pos())
-		diFile = d.getFile(file)
-	}
+	diFile, line := d.position(t.Obj().Pos())
 
 	// Create a placeholder for the named type, to terminate cycles.
 	name := t.Obj().Name()
@@ -332,8 +634,16 @@ func (d *DIBuilder) descriptorNamed(t *types.Named) llvm.Metadata {
 	})
 	d.types.Set(t, placeholder)
 
+	// A struct is described at the definition of the named type,
+	// rather than shared with identical struct types.
+	var underlying llvm.Metadata
+	if st, ok := t.Underlying().(*types.Struct); ok {
+		underlying = d.descriptorStruct(st, name, t.Obj().Pos())
+	} else {
+		underlying = d.DIType(t.Underlying())
+	}
 	typedef := d.builder.CreateTypedef(llvm.DITypedef{
-		Type: d.DIType(t.Underlying()),
+		Type: underlying,
 		Name: name,
 		File: diFile,
 		Line: line,
@@ -361,20 +671,59 @@ func (d *DIBuilder) descriptorSlice(t *types.Slice, name string) llvm.Metadata {
 }
 
 func (d *DIBuilder) descriptorMap(t *types.Map, name string) llvm.Metadata {
-	// FIXME: This should be DW_TAG_pointer_type to __go_map.
-	return d.descriptorBasic(types.Typ[types.Uintptr], name)
+	// Describe the map entries of this particular map type, so that
+	// the buckets may be walked without consulting the descriptor.
+	entry := types.NewNamed(types.NewTypeName(0, nil, "__go_map_entry", nil), nil, nil)
+	entry.SetUnderlying(types.NewStruct([]*types.Var{
+		field("__next", types.NewPointer(entry)),
+		field("__key", t.Key()),
+		field("__val", t.Elem()),
+	}, nil))
+	mapStruct := types.NewNamed(types.NewTypeName(0, nil, "__go_map", nil), types.NewStruct([]*types.Var{
+		field("__descriptor", types.NewPointer(mapDescriptorType)),
+		field("__element_count", types.Typ[types.Uintptr]),
+		field("__bucket_count", types.Typ[types.Uintptr]),
+		field("__buckets", types.NewPointer(types.NewPointer(entry))),
+	}, nil), nil)
+	return d.builder.CreatePointerType(llvm.DIPointerType{
+		Pointee:     d.DIType(mapStruct),
+		SizeInBits:  uint64(d.sizes.Sizeof(t) * 8),
+		AlignInBits: uint64(d.sizes.Alignof(t) * 8),
+		Name:        name,
+	})
 }
 
 func (d *DIBuilder) descriptorChan(t *types.Chan, name string) llvm.Metadata {
-	// FIXME: This should be DW_TAG_pointer_type to __go_channel.
-	return d.descriptorBasic(types.Typ[types.Uintptr], name)
+	return d.builder.CreatePointerType(llvm.DIPointerType{
+		Pointee:     d.DIType(channelType),
+		SizeInBits:  uint64(d.sizes.Sizeof(t) * 8),
+		AlignInBits: uint64(d.sizes.Alignof(t) * 8),
+		Name:        name,
+	})
 }
 
 func (d *DIBuilder) descriptorInterface(t *types.Interface, name string) llvm.Metadata {
-	ifaceStruct := types.NewStruct([]*types.Var{
-		types.NewVar(0, nil, "type", types.NewPointer(types.Typ[types.Uint8])),
-		types.NewVar(0, nil, "data", types.NewPointer(types.Typ[types.Uint8])),
-	}, nil)
+	typeDescriptorPtr := types.NewPointer(typeDescriptorType)
+	var ifaceStruct *types.Struct
+	if t.NumMethods() == 0 {
+		ifaceStruct = types.NewStruct([]*types.Var{
+			field("__type_descriptor", typeDescriptorPtr),
+			field("__object", types.Typ[types.UnsafePointer]),
+		}, nil)
+	} else {
+		// The method table begins with the type descriptor of the
+		// dynamic type, followed by the methods in sorted order.
+		methods := make([]*types.Var, t.NumMethods()+1)
+		methods[0] = field("__type_descriptor", typeDescriptorPtr)
+		for i := 1; i < len(methods); i++ {
+			m := t.Method(i - 1)
+			methods[i] = types.NewVar(m.Pos(), nil, m.Name(), types.Typ[types.UnsafePointer])
+		}
+		ifaceStruct = types.NewStruct([]*types.Var{
+			field("__methods", types.NewPointer(types.NewStruct(methods, nil))),
+			field("__object", types.Typ[types.UnsafePointer]),
+		}, nil)
+	}
 	return d.typeDebugDescriptor(ifaceStruct, name)
 }
 
@@ -430,7 +779,8 @@ func (d *DIBuilder) descriptorSignature(t *types.Signature, name string) llvm.Me
 		paramTypes = []llvm.Metadata{returnType}
 	}
 
-	// TODO(axw) get position of type definition for File field
+	// Signatures are not declared in their own right, so subroutine
+	// types have no file.
 	return d.builder.CreateSubroutineType(llvm.DISubroutineType{
 		Parameters: paramTypes,
 	})
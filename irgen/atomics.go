@@ -0,0 +1,156 @@
+//===- atomics.go - IR generation for atomic operations -------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements IR generation for calls to the functions of the
+// sync/atomic package, which are lowered to sequentially consistent LLVM
+// atomic instructions.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+import (
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+type atomicOp int
+
+const (
+	atomicLoad atomicOp = iota
+	atomicStore
+	atomicAdd
+	atomicSwap
+	atomicCompareAndSwap
+)
+
+// atomicFuncs maps a package path and function name to the atomic
+// operation performed by the function. Each function takes the address
+// of the operand as its first argument.
+var atomicFuncs = map[string]map[string]atomicOp{
+	"sync/atomic": {
+		"AddInt32":   atomicAdd,
+		"AddInt64":   atomicAdd,
+		"AddUint32":  atomicAdd,
+		"AddUint64":  atomicAdd,
+		"AddUintptr": atomicAdd,
+
+		"CompareAndSwapInt32":   atomicCompareAndSwap,
+		"CompareAndSwapInt64":   atomicCompareAndSwap,
+		"CompareAndSwapUint32":  atomicCompareAndSwap,
+		"CompareAndSwapUint64":  atomicCompareAndSwap,
+		"CompareAndSwapUintptr": atomicCompareAndSwap,
+		"CompareAndSwapPointer": atomicCompareAndSwap,
+
+		"LoadInt32":   atomicLoad,
+		"LoadInt64":   atomicLoad,
+		"LoadUint32":  atomicLoad,
+		"LoadUint64":  atomicLoad,
+		"LoadUintptr": atomicLoad,
+		"LoadPointer": atomicLoad,
+
+		"StoreInt32":   atomicStore,
+		"StoreInt64":   atomicStore,
+		"StoreUint32":  atomicStore,
+		"StoreUint64":  atomicStore,
+		"StoreUintptr": atomicStore,
+		"StorePointer": atomicStore,
+
+		"SwapInt32":   atomicSwap,
+		"SwapInt64":   atomicSwap,
+		"SwapUint32":  atomicSwap,
+		"SwapUint64":  atomicSwap,
+		"SwapUintptr": atomicSwap,
+		"SwapPointer": atomicSwap,
+	},
+}
+
+// lookupAtomic returns the atomic operation performed by fn, if any.
+func lookupAtomic(fn *ssa.Function) (atomicOp, bool) {
+	if fn.Pkg == nil || fn.Signature.Recv() != nil {
+		return 0, false
+	}
+	op, ok := atomicFuncs[fn.Pkg.Object.Path()][fn.Name()]
+	return op, ok
+}
+
+// atomicCall emits an atomic instruction in place of a call to fn, if fn
+// is one of the functions listed in atomicFuncs, and returns the results
+// of the call.
+func (fr *frame) atomicCall(fn *ssa.Function, args []ssa.Value) ([]*govalue, bool) {
+	op, ok := lookupAtomic(fn)
+	if !ok {
+		return nil, false
+	}
+
+	// The type of the operand is that of the result for those
+	// operations that return it, and of the second argument otherwise.
+	var typ types.Type
+	switch op {
+	case atomicLoad, atomicAdd, atomicSwap:
+		typ = fn.Signature.Results().At(0).Type()
+	default:
+		typ = args[1].Type()
+	}
+
+	// LLVM's atomicrmw only operates on integers, so pointer operands
+	// are converted to and from uintptr.
+	lltyp := fr.llvmtypes.ToLLVM(typ)
+	isptr := lltyp.TypeKind() == llvm.PointerTypeKind
+	if isptr {
+		lltyp = fr.types.inttype
+	}
+	align := int(fr.llvmtypes.Sizeof(typ))
+	operand := func(v ssa.Value) llvm.Value {
+		llv := fr.llvmvalue(v)
+		if isptr {
+			llv = fr.builder.CreatePtrToInt(llv, lltyp, "")
+		}
+		return llv
+	}
+	result := func(v llvm.Value) []*govalue {
+		if isptr {
+			v = fr.builder.CreateIntToPtr(v, llvm.PointerType(llvm.Int8Type(), 0), "")
+		}
+		return []*govalue{newValue(v, typ)}
+	}
+
+	const ordering = llvm.AtomicOrderingSequentiallyConsistent
+	addr := fr.builder.CreateBitCast(fr.llvmvalue(args[0]), llvm.PointerType(lltyp, 0), "")
+	switch op {
+	case atomicLoad:
+		v := fr.builder.CreateLoad(addr, "")
+		v.SetOrdering(ordering)
+		v.SetAlignment(align)
+		return result(v), true
+
+	case atomicStore:
+		st := fr.builder.CreateStore(operand(args[1]), addr)
+		st.SetOrdering(ordering)
+		st.SetAlignment(align)
+		return nil, true
+
+	case atomicAdd:
+		delta := operand(args[1])
+		old := fr.builder.CreateAtomicRMW(llvm.AtomicRMWBinOpAdd, addr, delta, ordering, false)
+		return result(fr.builder.CreateAdd(old, delta, "")), true
+
+	case atomicSwap:
+		old := fr.builder.CreateAtomicRMW(llvm.AtomicRMWBinOpXchg, addr, operand(args[1]), ordering, false)
+		return result(old), true
+
+	case atomicCompareAndSwap:
+		pair := fr.builder.CreateAtomicCmpXchg(addr, operand(args[1]), operand(args[2]), ordering, ordering, false)
+		swapped := fr.builder.CreateExtractValue(pair, 1, "")
+		swapped = fr.builder.CreateZExt(swapped, llvm.Int8Type(), "")
+		return []*govalue{newValue(swapped, fn.Signature.Results().At(0).Type())}, true
+	}
+	panic("unreachable")
+}
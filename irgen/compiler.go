@@ -36,6 +36,7 @@ type Module struct {
 	llvm.Module
 	Path       string
 	ExportData []byte
+	InlineData []byte
 	Package    *types.Package
 	disposed   bool
 }
@@ -50,6 +51,24 @@ func (m *Module) Dispose() {
 
 ///////////////////////////////////////////////////////////////////////////////
 
+// FPContractMode determines whether floating-point multiplications and
+// additions may be contracted into fused multiply-add operations.
+type FPContractMode int
+
+const (
+	// FPContractOff rounds the result of each operation, as in the Go
+	// specification.
+	FPContractOff FPContractMode = iota
+
+	// FPContractOn contracts a multiplication into the addition or
+	// subtraction that is its only use.
+	FPContractOn
+
+	// FPContractFast contracts a multiplication into each of the
+	// additions and subtractions that use it.
+	FPContractFast
+)
+
 type CompilerOptions struct {
 	// TargetTriple is the LLVM triple for the target.
 	TargetTriple string
@@ -62,6 +81,16 @@ type CompilerOptions struct {
 	// replacement prefixes, to be applied in debug info.
 	DebugPrefixMaps []debug.PrefixMap
 
+	// SplitDwarfFile is the name of the file to which the debug info is
+	// to be split from the object file, if any. It is recorded in the
+	// skeleton compile unit left in the object file.
+	SplitDwarfFile string
+
+	// GdbScript is the path of a Python script for gdb to load when
+	// debugging programs containing the module. If GenerateDebug is
+	// set, the script is referenced from the .debug_gdb_scripts section.
+	GdbScript string
+
 	// Logger is a logger used for tracing compilation.
 	Logger *log.Logger
 
@@ -84,6 +113,20 @@ type CompilerOptions struct {
 	// dynamic instrumentation using a sanitizer.
 	SanitizerAttribute llvm.Attribute
 
+	// NoSplitStack disables split stacks. Functions are emitted without
+	// the __morestack prologue, and must be linked against a runtime
+	// that allocates fixed-size goroutine stacks.
+	NoSplitStack bool
+
+	// OptimizeSiblingCalls allows calls in tail position to be emitted
+	// as sibling calls, at the expense of omitting the caller's frame
+	// from backtraces.
+	OptimizeSiblingCalls bool
+
+	// StackProtector is the stack protector attribute (ssp, sspstrong or
+	// sspreq) to apply to functions, or zero for none.
+	StackProtector llvm.Attribute
+
 	// Importer is the importer. If nil, the compiler will set this field
 	// automatically using MakeImporter().
 	Importer types.Importer
@@ -104,6 +147,43 @@ type CompilerOptions struct {
 
 	// Packages is used by go/types as the imported package map if non-nil.
 	Packages map[string]*types.Package
+
+	// WholeProgram enables whole-program analysis when compiling the main
+	// package. Methods that are found to be unreachable are not emitted,
+	// and are omitted from type descriptors and interface method tables.
+	WholeProgram bool
+
+	// EvalInit enables evaluation of package initializers at compile
+	// time. Initializers that can be evaluated without side effects
+	// outside of the package's globals are emitted as static data.
+	EvalInit bool
+
+	// TargetCPU and TargetFeatures are the CPU and comma-separated target
+	// features, as passed to the LLVM target machine. They are recorded as
+	// attributes of each function defined, so that they are preserved
+	// through LTO.
+	TargetCPU      string
+	TargetFeatures string
+
+	// FPContract determines whether floating-point operations may be
+	// contracted into fused multiply-add operations.
+	FPContract FPContractMode
+
+	// UnsafeMath allows floating-point optimizations that may violate
+	// IEEE semantics, such as reassociation and ignoring the sign of zero.
+	UnsafeMath bool
+
+	// FiniteMath allows floating-point optimizations that assume that
+	// operands and results are neither NaNs nor infinities.
+	FiniteMath bool
+
+	// InlineThreshold is the maximum cost of a function whose body is
+	// inlined at its call sites, as computed by ssaopt.InlineCost. The
+	// bodies of functions with external linkage within the threshold are
+	// also made available to importing packages; these include unexported
+	// functions that exported inlinable bodies may call. Zero disables
+	// inlining.
+	InlineThreshold int
 }
 
 type Compiler struct {
@@ -144,6 +224,9 @@ type compiler struct {
 	llvmtypes *llvmTypeMap
 	types     *TypeMap
 
+	// reachability is non-nil in whole-program mode.
+	reachability *reachability
+
 	debug *debug.DIBuilder
 }
 
@@ -154,11 +237,35 @@ func (c *compiler) logf(format string, v ...interface{}) {
 }
 
 func (c *compiler) addCommonFunctionAttrs(fn llvm.Value) {
-	fn.AddTargetDependentFunctionAttr("disable-tail-calls", "true")
-	fn.AddTargetDependentFunctionAttr("split-stack", "")
+	if !c.OptimizeSiblingCalls {
+		fn.AddTargetDependentFunctionAttr("disable-tail-calls", "true")
+	}
+	if !c.NoSplitStack {
+		fn.AddTargetDependentFunctionAttr("split-stack", "")
+	}
+	if c.TargetCPU != "" {
+		fn.AddTargetDependentFunctionAttr("target-cpu", c.TargetCPU)
+	}
+	if c.TargetFeatures != "" {
+		fn.AddTargetDependentFunctionAttr("target-features", c.TargetFeatures)
+	}
+	if c.UnsafeMath {
+		fn.AddTargetDependentFunctionAttr("unsafe-fp-math", "true")
+		fn.AddTargetDependentFunctionAttr("no-signed-zeros-fp-math", "true")
+	}
+	if c.FiniteMath {
+		fn.AddTargetDependentFunctionAttr("no-infs-fp-math", "true")
+		fn.AddTargetDependentFunctionAttr("no-nans-fp-math", "true")
+	}
+	if c.FPContract == FPContractFast {
+		fn.AddTargetDependentFunctionAttr("less-precise-fpmad", "true")
+	}
 	if attr := c.SanitizerAttribute; attr != 0 {
 		fn.AddFunctionAttr(attr)
 	}
+	if attr := c.StackProtector; attr != 0 {
+		fn.AddFunctionAttr(attr)
+	}
 }
 
 // MakeImporter sets CompilerOptions.Importer to an appropriate importer
@@ -167,19 +274,30 @@ func (c *compiler) addCommonFunctionAttrs(fn llvm.Value) {
 // If CompilerOptions.GccgoPath is non-empty, the importer will also use
 // the search paths for that gccgo installation.
 func (opts *CompilerOptions) MakeImporter() error {
+	paths, err := opts.importSearchPaths()
+	if err != nil {
+		return err
+	}
 	opts.InitMap = make(map[*types.Package]gccgoimporter.InitData)
-	if opts.GccgoPath == "" {
-		paths := append(append([]string{}, opts.ImportPaths...), ".")
-		opts.Importer = gccgoimporter.GetImporter(paths, opts.InitMap)
-	} else {
+	opts.Importer = gccgoimporter.GetImporter(paths, opts.InitMap)
+	return nil
+}
+
+// importSearchPaths returns the paths searched for export data: those in
+// CompilerOptions.ImportPaths, followed by the search paths for the gccgo
+// installation at CompilerOptions.GccgoPath, if any, and the current
+// directory.
+func (opts *CompilerOptions) importSearchPaths() ([]string, error) {
+	paths := append([]string{}, opts.ImportPaths...)
+	if opts.GccgoPath != "" {
 		var inst gccgoimporter.GccgoInstallation
 		err := inst.InitFromDriver(opts.GccgoPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		opts.Importer = inst.GetImporter(opts.ImportPaths, opts.InitMap)
+		paths = append(paths, inst.SearchPaths()...)
 	}
-	return nil
+	return append(paths, "."), nil
 }
 
 func (compiler *compiler) compile(fset *token.FileSet, astFiles []*ast.File, importpath string) (m *Module, err error) {
@@ -221,6 +339,11 @@ func (compiler *compiler) compile(fset *token.FileSet, astFiles []*ast.File, imp
 	program := ssa.Create(iprog, ssa.BareInits)
 	mainPkginfo := iprog.InitialPackages()[0]
 	mainPkg := program.CreatePackage(mainPkginfo)
+	if compiler.GenerateDebug {
+		// Record references to source variables, from which
+		// variable debug info is generated.
+		mainPkg.SetDebugMode(true)
+	}
 
 	// Create a Module, which contains the LLVM module.
 	modulename := importpath
@@ -229,7 +352,7 @@ func (compiler *compiler) compile(fset *token.FileSet, astFiles []*ast.File, imp
 	compiler.module.SetDataLayout(compiler.dataLayout)
 
 	// Create a new translation unit.
-	unit := newUnit(compiler, mainPkg)
+	unit := newUnit(compiler, mainPkg, mainPkginfo)
 
 	// Create the runtime interface.
 	compiler.runtime, err = newRuntimeInterface(compiler.module.Module, compiler.llvmtypes)
@@ -249,24 +372,46 @@ func (compiler *compiler) compile(fset *token.FileSet, astFiles []*ast.File, imp
 		MethodResolver(unit),
 	)
 
+	if compiler.WholeProgram && importpath == "main" {
+		compiler.reachability = analyzeReachability(mainPkg)
+		compiler.types.reachability = compiler.reachability
+	}
+
 	if compiler.GenerateDebug {
 		compiler.debug = debug.NewDIBuilder(
 			types.Sizes(compiler.llvmtypes),
 			compiler.module.Module,
 			impcfg.Fset,
 			compiler.DebugPrefixMaps,
+			compiler.SplitDwarfFile,
 		)
 		defer compiler.debug.Destroy()
-		defer compiler.debug.Finalize()
+		compiler.debug.SetScopes(mainPkginfo.Scopes)
+		if compiler.GdbScript != "" {
+			compiler.debug.EmbedGdbScript(compiler.GdbScript)
+		}
 	}
 
 	unit.translatePackage(mainPkg)
 	compiler.processAnnotations(unit, mainPkginfo)
 
+	// The debug metadata must be complete before the module is copied
+	// to build the inline data.
+	if compiler.GenerateDebug {
+		compiler.debug.Finalize()
+	}
+
 	if importpath == "main" {
 		compiler.createInitMainFunction(mainPkg)
 	} else {
 		compiler.module.ExportData = compiler.buildExportData(mainPkg)
+		if compiler.inlining() {
+			compiler.module.InlineData = unit.buildInlineData()
+		}
+	}
+
+	if compiler.inlining() {
+		compiler.linkInlineData(mainPkg.Object)
 	}
 
 	return compiler.module, nil
@@ -0,0 +1,224 @@
+//===- defers.go - IR generation for open-coded defers --------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements IR generation for open-coded defers. In a function
+// whose only defer statement executes at most once, and in which nothing
+// else can panic, the arguments of the deferred call are stored in a stack
+// slot and the call is made directly on return.
+//
+// The runtime runs the defer chain of a panicking goroutine before the stack
+// is unwound, and runtime.Goexit never unwinds it, so a deferred call which
+// is not registered with the runtime would be skipped by both, and could not
+// recover. The restrictions ensure that the function cannot panic or exit
+// while its open-coded call is pending.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+import (
+	"go/token"
+
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// openDefer holds the state of an open-coded defer statement.
+type openDefer struct {
+	instr *ssa.Defer
+	args  []ssa.Value
+
+	// slot holds the arguments of the deferred call, and is null if the
+	// thunk takes no arguments. slotType is the type of a pointer to it.
+	slot     llvm.Value
+	slotType llvm.Type
+
+	// flag is an i1 stack slot which is set while the deferred call is
+	// pending.
+	flag llvm.Value
+
+	// thunk performs the deferred call.
+	thunk llvm.Value
+}
+
+// openCodedDefer returns the defer statement of f if it may be open-coded,
+// or nil otherwise. This is the case if f contains exactly one defer
+// statement, which is not in a loop and calls a known function which does
+// not call recover, and no other instruction in f may panic.
+func openCodedDefer(f *ssa.Function) *ssa.Defer {
+	var d *ssa.Defer
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if instr, ok := instr.(*ssa.Defer); ok {
+				if d != nil {
+					return nil
+				}
+				d = instr
+				continue
+			}
+			if mayPanic(instr) {
+				return nil
+			}
+		}
+	}
+	if d == nil {
+		return nil
+	}
+
+	callee := d.Call.StaticCallee()
+	if callee == nil || callee.Synthetic != "" || len(callee.Blocks) == 0 || callsRecover(callee) {
+		return nil
+	}
+
+	// The defer is in a loop if its block is reachable from itself.
+	b := d.Block()
+	seen := make(map[*ssa.BasicBlock]bool)
+	work := append([]*ssa.BasicBlock{}, b.Succs...)
+	for len(work) != 0 {
+		w := work[len(work)-1]
+		work = work[:len(work)-1]
+		if w == b {
+			return nil
+		}
+		if !seen[w] {
+			seen[w] = true
+			work = append(work, w.Succs...)
+		}
+	}
+	return d
+}
+
+// mayPanic reports whether instr may panic or call runtime.Goexit, either
+// itself or through a function which it calls.
+func mayPanic(instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.Alloc, *ssa.ChangeInterface, *ssa.ChangeType, *ssa.Convert,
+		*ssa.DebugRef, *ssa.Extract, *ssa.Field, *ssa.If, *ssa.Jump,
+		*ssa.MakeClosure, *ssa.MakeInterface, *ssa.Next, *ssa.Phi,
+		*ssa.Range, *ssa.Return, *ssa.RunDefers:
+		return false
+
+	case *ssa.BinOp:
+		switch instr.Op {
+		case token.QUO, token.REM:
+			return isInteger(instr.Type())
+		case token.EQL, token.NEQ:
+			// Comparing interfaces panics if their dynamic type
+			// is not comparable.
+			switch instr.X.Type().Underlying().(type) {
+			case *types.Basic, *types.Pointer, *types.Chan:
+				return false
+			}
+			return true
+		}
+		return false
+
+	case *ssa.UnOp:
+		return instr.Op == token.MUL && !isNonNilAddr(instr.X)
+
+	case *ssa.Store:
+		return !isNonNilAddr(instr.Addr)
+
+	case *ssa.FieldAddr:
+		return !isNonNilAddr(instr.X)
+
+	case *ssa.Call:
+		if b, ok := instr.Call.Value.(*ssa.Builtin); ok {
+			switch b.Name() {
+			case "append", "cap", "complex", "copy", "imag", "len", "print", "println", "real":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isNonNilAddr reports whether v is an address which cannot be nil.
+func isNonNilAddr(v ssa.Value) bool {
+	switch v := v.(type) {
+	case *ssa.Alloc, *ssa.Global:
+		return true
+	case *ssa.FieldAddr:
+		return isNonNilAddr(v.X)
+	}
+	return false
+}
+
+// callsRecover reports whether f calls the recover builtin.
+func callsRecover(f *ssa.Function) bool {
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				if b, ok := call.Call.Value.(*ssa.Builtin); ok && b.Name() == "recover" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// setupOpenDefer allocates and initializes the stack slots for the
+// open-coded defer of f, if any, and creates its thunk. The builder must be
+// positioned in the prologue.
+func (fr *frame) setupOpenDefer(f *ssa.Function) {
+	d := openCodedDefer(f)
+	if d == nil {
+		return
+	}
+
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	od := &openDefer{instr: d, slot: llvm.ConstNull(i8ptr)}
+	args, structtype := thunkArgs(d)
+	if len(args) != 0 {
+		slot := fr.builder.CreateAlloca(fr.llvmtypes.ToLLVM(structtype), "")
+		od.args = args
+		od.slotType = slot.Type()
+		od.slot = fr.builder.CreateBitCast(slot, i8ptr, "")
+	}
+	od.flag = fr.builder.CreateAlloca(llvm.Int1Type(), "")
+	fr.builder.CreateStore(llvm.ConstNull(llvm.Int1Type()), od.flag)
+	od.thunk = fr.createThunkFunc(d, od.args, od.slotType, false, false)
+	fr.openDefer = od
+}
+
+// deferOpen emits the code for the open-coded defer statement, which stores
+// the arguments of the deferred call and marks it as pending.
+func (fr *frame) deferOpen() {
+	od := fr.openDefer
+	if len(od.args) != 0 {
+		slot := fr.builder.CreateBitCast(od.slot, od.slotType, "")
+		for i, arg := range od.args {
+			argptr := fr.builder.CreateStructGEP(slot, i, "")
+			fr.builder.CreateStore(fr.llvmvalue(arg), argptr)
+		}
+	}
+	fr.builder.CreateStore(llvm.ConstAllOnes(llvm.Int1Type()), od.flag)
+}
+
+// runOpenDefer calls the open-coded deferred function if it is pending. It
+// is marked as no longer pending first, so that it is not run again if it
+// panics and the function recovers.
+func (fr *frame) runOpenDefer() {
+	od := fr.openDefer
+	pendingbb := llvm.AddBasicBlock(fr.function, "")
+	contbb := llvm.AddBasicBlock(fr.function, "")
+	pending := fr.builder.CreateLoad(od.flag, "")
+	fr.builder.CreateCondBr(pending, pendingbb, contbb)
+
+	fr.builder.SetInsertPointAtEnd(pendingbb)
+	fr.builder.CreateStore(llvm.ConstNull(llvm.Int1Type()), od.flag)
+	callbb := llvm.AddBasicBlock(fr.function, "")
+	fr.builder.CreateInvoke(od.thunk, []llvm.Value{od.slot}, callbb, fr.unwindBlock, "")
+	fr.builder.SetInsertPointAtEnd(callbb)
+	fr.builder.CreateBr(contbb)
+
+	fr.builder.SetInsertPointAtEnd(contbb)
+}
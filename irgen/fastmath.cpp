@@ -0,0 +1,34 @@
+//===- fastmath.cpp - floating-point instruction flags --------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file defines the C function used by fastmath.go to set the fast-math
+// flags of an instruction.
+//
+//===----------------------------------------------------------------------===//
+
+#include "llvm-c/Core.h"
+#include "llvm/IR/Instruction.h"
+#include "llvm/IR/Operator.h"
+
+using namespace llvm;
+
+extern "C" void llgoSetFastMathFlags(LLVMValueRef inst, unsigned flags) {
+  FastMathFlags FMF;
+  if (flags & 1)
+    FMF.setNoNaNs();
+  if (flags & 2)
+    FMF.setNoInfs();
+  if (flags & 4)
+    FMF.setNoSignedZeros();
+  if (flags & 8)
+    FMF.setAllowReciprocal();
+  if (flags & 16)
+    FMF.setUnsafeAlgebra();
+  unwrap<Instruction>(inst)->setFastMathFlags(FMF);
+}
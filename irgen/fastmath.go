@@ -0,0 +1,72 @@
+//===- fastmath.go - floating-point instruction flags ---------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements the fast-math flags of floating-point instructions,
+// which the LLVM C API does not expose.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+/*
+#include "llvm-c/Core.h"
+
+void llgoSetFastMathFlags(LLVMValueRef inst, unsigned flags);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// The fast-math flags of a floating-point instruction. These must match
+// the flags handled by llgoSetFastMathFlags.
+const (
+	fastMathNoNaNs = 1 << iota
+	fastMathNoInfs
+	fastMathNoSignedZeros
+	fastMathAllowReciprocal
+	fastMathUnsafeAlgebra
+)
+
+// fastMathFlags returns the fast-math flags of the floating-point
+// instructions emitted, according to the floating-point optimization
+// options: the "fast" flag if both unsafe and finite math optimizations
+// are allowed, as by -ffast-math, or else the flags implied by each.
+func (c *compiler) fastMathFlags() uint {
+	var flags uint
+	if c.UnsafeMath {
+		flags |= fastMathNoSignedZeros | fastMathAllowReciprocal
+	}
+	if c.FiniteMath {
+		flags |= fastMathNoNaNs | fastMathNoInfs
+	}
+	if c.UnsafeMath && c.FiniteMath {
+		flags |= fastMathUnsafeAlgebra
+	}
+	return flags
+}
+
+// setFastMathFlags sets the fast-math flags of the floating-point
+// instruction inst, if any are allowed.
+func (c *compiler) setFastMathFlags(inst llvm.Value) {
+	if flags := c.fastMathFlags(); flags != 0 && !inst.IsAInstruction().IsNil() {
+		C.llgoSetFastMathFlags(C.LLVMValueRef(unsafe.Pointer(inst.C)), C.unsigned(flags))
+	}
+}
+
+// createFBinOp emits the floating-point arithmetic instruction op, with
+// the fast-math flags allowed.
+func (fr *frame) createFBinOp(op llvm.Opcode, lhs, rhs llvm.Value) llvm.Value {
+	result := fr.builder.CreateBinOp(op, lhs, rhs, "")
+	fr.setFastMathFlags(result)
+	return result
+}
@@ -20,10 +20,9 @@ import (
 	"llvm.org/llvm/bindings/go/llvm"
 )
 
-// createThunk creates a thunk from a
-// given function and arguments, suitable for use with
-// "defer" and "go".
-func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg llvm.Value) {
+// thunkArgs returns the values that must be passed to a thunk for the
+// given call, and the type of the structure in which they are passed.
+func thunkArgs(call ssa.CallInstruction) ([]ssa.Value, *types.Struct) {
 	seenarg := make(map[ssa.Value]bool)
 	var args []ssa.Value
 	var argtypes []*types.Var
@@ -46,6 +45,14 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 	for _, arg := range call.Common().Args {
 		packArg(arg)
 	}
+	return args, types.NewStruct(argtypes, nil)
+}
+
+// createThunk creates a thunk from a
+// given function and arguments, suitable for use with
+// "defer" and "go".
+func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg llvm.Value) {
+	args, structtype := thunkArgs(call)
 
 	var isRecoverCall bool
 	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
@@ -62,7 +69,6 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 			arg = llvm.ConstPointerNull(i8ptr)
 		}
 	} else {
-		structtype := types.NewStruct(argtypes, nil)
 		arg = fr.createTypeMalloc(structtype)
 		structllptr = arg.Type()
 		for i, ssaarg := range args {
@@ -72,6 +78,18 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 		arg = fr.builder.CreateBitCast(arg, i8ptr, "")
 	}
 
+	_, isDefer := call.(*ssa.Defer)
+	thunkfn := fr.createThunkFunc(call, args, structllptr, isRecoverCall, isDefer)
+	thunk = fr.builder.CreateBitCast(thunkfn, i8ptr, "")
+	return
+}
+
+// createThunkFunc creates the function for a thunk, which unpacks args
+// from a structure of type structllptr and performs the call. If isDefer
+// is set, the thunk is suitable for calling from the runtime's defer
+// mechanism.
+func (fr *frame) createThunkFunc(call ssa.CallInstruction, args []ssa.Value, structllptr llvm.Type, isRecoverCall, isDefer bool) llvm.Value {
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
 	thunkfntype := llvm.FunctionType(llvm.VoidType(), []llvm.Type{i8ptr}, false)
 	thunkfn := llvm.AddFunction(fr.module.Module, "", thunkfntype)
 	thunkfn.SetLinkage(llvm.InternalLinkage)
@@ -97,8 +115,6 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 		}
 	}
 
-	_, isDefer := call.(*ssa.Defer)
-
 	entrybb := llvm.AddBasicBlock(thunkfn, "entry")
 	br := thunkfr.builder.CreateBr(entrybb)
 	thunkfr.allocaBuilder.SetInsertPointBefore(br)
@@ -120,6 +136,5 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 	}
 	thunkfr.builder.CreateRetVoid()
 
-	thunk = fr.builder.CreateBitCast(thunkfn, i8ptr, "")
-	return
+	return thunkfn
 }
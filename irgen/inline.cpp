@@ -0,0 +1,23 @@
+//===- inline.cpp - cross-package inlining --------------------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file defines the C function used by inline.go to strip the debug info
+// of imported inline data.
+//
+//===----------------------------------------------------------------------===//
+
+#include "llvm-c/Core.h"
+#include "llvm/IR/DebugInfo.h"
+#include "llvm/IR/Module.h"
+
+using namespace llvm;
+
+extern "C" void llgoStripDebugInfo(LLVMModuleRef m) {
+  StripDebugInfo(*unwrap(m));
+}
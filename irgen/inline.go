@@ -0,0 +1,264 @@
+//===- inline.go - cross-package inlining ---------------------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements the export and import of inlinable function bodies.
+// A package's object file carries the bitcode for its small exported
+// functions in a separate section; packages that import it link these in
+// as available_externally definitions, which LLVM may then inline.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+/*
+#include "llvm-c/Core.h"
+
+void llgoStripDebugInfo(LLVMModuleRef m);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"llvm.org/llgo/ssaopt"
+	"llvm.org/llgo/third_party/gotools/go/gccgoimporter"
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/ssa/ssautil"
+	"llvm.org/llgo/third_party/gotools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// inlineSection is the name of the object file section holding the
+// bitcode for a package's inlinable functions.
+const inlineSection = ".go_inline"
+
+// inlining reports whether inlining is enabled. Inlined bodies carry the
+// debug info of the exporting package, and LLVM describes the inlined
+// frames using the debug location of each call site. If the importing
+// package is compiled without debug info, the bodies are stripped of it.
+func (c *compiler) inlining() bool {
+	return c.InlineThreshold > 0
+}
+
+// exportsInlineBody reports whether the body of f should be made available
+// to importing packages.
+func (u *unit) exportsInlineBody(f *ssa.Function) bool {
+	if f.Pkg != u.pkg || f.Parent() != nil || f.Synthetic != "" || f.Name() == "init" {
+		return false
+	}
+	if u.getFunctionLinkage(f) != llvm.ExternalLinkage {
+		return false
+	}
+	return ssa.CanInline(f) && ssaopt.InlineCost(f) <= u.InlineThreshold
+}
+
+// buildInlineData returns the bitcode for a module containing the
+// available_externally definitions of the package's inlinable functions,
+// together with the declarations and constant data they refer to.
+// Functions that refer to symbols local to this package are excluded.
+func (u *unit) buildInlineData() []byte {
+	var names []string
+	for f := range ssautil.AllFunctions(u.pkg.Prog) {
+		if !u.exportsInlineBody(f) {
+			continue
+		}
+		if llfn, ok := u.globals[f]; ok {
+			names = append(names, llfn.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	bc := llvm.WriteBitcodeToMemoryBuffer(u.module.Module)
+	m, err := llvm.ParseBitcode(bc)
+	bc.Dispose()
+	if err != nil {
+		u.logf("Failed to build inline data: %v", err)
+		return nil
+	}
+	defer m.Dispose()
+
+	// Determine which functions to keep: the inlinable functions, and
+	// any discardable functions that they refer to.
+	keep := make(map[llvm.Value]bool)
+	var inlinable []llvm.Value
+	for _, name := range names {
+		fn := m.NamedFunction(name)
+		refs := make(map[llvm.Value]bool)
+		if !exportableBody(fn, refs) {
+			continue
+		}
+		for ref := range refs {
+			keep[ref] = true
+		}
+		inlinable = append(inlinable, fn)
+	}
+	if len(inlinable) == 0 {
+		return nil
+	}
+
+	// Reduce everything else to declarations, and let global DCE remove
+	// whatever is no longer referenced.
+	var appending []llvm.Value
+	for fn := m.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if !fn.IsDeclaration() && !keep[fn] {
+			deleteFunctionBody(fn)
+			fn.SetLinkage(llvm.ExternalLinkage)
+		}
+	}
+	for g := m.FirstGlobal(); !g.IsNil(); g = llvm.NextGlobal(g) {
+		switch g.Linkage() {
+		case llvm.ExternalLinkage:
+			if !g.IsDeclaration() {
+				g.SetLinkage(llvm.AvailableExternallyLinkage)
+			}
+		case llvm.AppendingLinkage:
+			appending = append(appending, g)
+		}
+	}
+	for _, g := range appending {
+		g.EraseFromParentAsGlobal()
+	}
+	pm := llvm.NewPassManager()
+	pm.AddGlobalDCEPass()
+	pm.Run(m)
+	pm.Dispose()
+
+	for _, fn := range inlinable {
+		fn.SetLinkage(llvm.AvailableExternallyLinkage)
+	}
+
+	u.logf("Exporting %d inlinable functions", len(inlinable))
+	mb := llvm.WriteBitcodeToMemoryBuffer(m)
+	defer mb.Dispose()
+	return append([]byte(nil), mb.Bytes()...)
+}
+
+// exportableRefs reports whether v may be referred to from another module,
+// either directly or by copying its definition into that module. The
+// functions and variables whose definitions must be copied are added to
+// refs.
+func exportableRefs(v llvm.Value, refs map[llvm.Value]bool) bool {
+	if refs[v] {
+		return true
+	}
+
+	switch {
+	case !v.IsAFunction().IsNil():
+		switch v.Linkage() {
+		case llvm.ExternalLinkage, llvm.ExternalWeakLinkage, llvm.AvailableExternallyLinkage:
+			return true
+		case llvm.InternalLinkage, llvm.PrivateLinkage:
+			return false
+		}
+		if v.IsDeclaration() {
+			return true
+		}
+		return exportableBody(v, refs)
+
+	case !v.IsAGlobalVariable().IsNil():
+		switch v.Linkage() {
+		case llvm.ExternalLinkage, llvm.ExternalWeakLinkage, llvm.AvailableExternallyLinkage:
+			return true
+		case llvm.InternalLinkage, llvm.PrivateLinkage:
+			if !v.IsGlobalConstant() {
+				return false
+			}
+		}
+		if v.IsDeclaration() {
+			return true
+		}
+		refs[v] = true
+		return exportableRefs(v.Initializer(), refs)
+
+	case !v.IsAGlobalValue().IsNil():
+		return false
+
+	case !v.IsAConstant().IsNil():
+		return exportableOperands(v, refs)
+	}
+
+	return true
+}
+
+// exportableBody reports whether the definition of the function fn may be
+// copied into another module, adding fn to refs along with the functions
+// and variables whose definitions it requires.
+func exportableBody(fn llvm.Value, refs map[llvm.Value]bool) bool {
+	refs[fn] = true
+	for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+		for instr := bb.FirstInstruction(); !instr.IsNil(); instr = llvm.NextInstruction(instr) {
+			if !exportableOperands(instr, refs) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// exportableOperands applies exportableRefs to each of v's operands.
+func exportableOperands(v llvm.Value, refs map[llvm.Value]bool) bool {
+	for i := 0; i != v.OperandsCount(); i++ {
+		op := v.Operand(i)
+		if op.IsNil() || op.IsBasicBlock() {
+			continue
+		}
+		if !exportableRefs(op, refs) {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteFunctionBody removes the body of fn, turning it into a declaration.
+func deleteFunctionBody(fn llvm.Value) {
+	for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+		for instr := bb.FirstInstruction(); !instr.IsNil(); instr = llvm.NextInstruction(instr) {
+			if instr.Type().TypeKind() != llvm.VoidTypeKind {
+				instr.ReplaceAllUsesWith(llvm.Undef(instr.Type()))
+			}
+		}
+	}
+	for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+		bb.LastInstruction().EraseFromParentAsInstruction()
+	}
+	for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = fn.FirstBasicBlock() {
+		bb.EraseFromParent()
+	}
+}
+
+// linkInlineData links the inlinable functions exported by each of pkg's
+// imports into the module.
+func (c *compiler) linkInlineData(pkg *types.Package) {
+	paths, err := c.importSearchPaths()
+	if err != nil {
+		c.logf("Not importing inline data: %v", err)
+		return
+	}
+	for _, imp := range pkg.Imports() {
+		data, err := gccgoimporter.ReadSection(paths, imp.Path(), inlineSection)
+		if err != nil || data == nil {
+			continue
+		}
+		mb := llvm.NewMemoryBufferFromRangeCopy(data)
+		m, err := llvm.ParseBitcode(mb)
+		mb.Dispose()
+		if err != nil {
+			c.logf("Failed to read inline data for %s: %v", imp.Path(), err)
+			continue
+		}
+		if !c.GenerateDebug {
+			C.llgoStripDebugInfo(C.LLVMModuleRef(unsafe.Pointer(m.C)))
+		}
+		if err := llvm.LinkModules(c.module.Module, m); err != nil {
+			c.logf("Failed to link inline data for %s: %v", imp.Path(), err)
+		}
+	}
+}
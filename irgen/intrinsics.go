@@ -0,0 +1,123 @@
+//===- intrinsics.go - IR generation for math intrinsics ------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements IR generation for calls to well-known functions of
+// the math package, which are lowered to LLVM intrinsics so that they may
+// be constant folded and vectorized.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+import (
+	"math"
+
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+type intrinsicOp int
+
+const (
+	intrinsicSqrt intrinsicOp = iota
+	intrinsicFabs
+	intrinsicFloor
+	intrinsicCeil
+	intrinsicTrunc
+	intrinsicCopysign
+	intrinsicFMulAdd
+)
+
+// intrinsicNames maps each operation to the name of the overloaded LLVM
+// intrinsic implementing it, less the type suffix.
+var intrinsicNames = [...]string{
+	intrinsicSqrt:     "llvm.sqrt",
+	intrinsicFabs:     "llvm.fabs",
+	intrinsicFloor:    "llvm.floor",
+	intrinsicCeil:     "llvm.ceil",
+	intrinsicTrunc:    "llvm.trunc",
+	intrinsicCopysign: "llvm.copysign",
+	intrinsicFMulAdd:  "llvm.fmuladd",
+}
+
+// mathIntrinsics maps the name of a function of the math package to the
+// intrinsic operation performed by the function. Each function takes
+// float64 operands. The math package of the supported Go version has no
+// FMA function, nor is there a math/bits package, so the integer bit
+// intrinsics are not used.
+var mathIntrinsics = map[string]intrinsicOp{
+	"Sqrt":     intrinsicSqrt,
+	"Abs":      intrinsicFabs,
+	"Floor":    intrinsicFloor,
+	"Ceil":     intrinsicCeil,
+	"Trunc":    intrinsicTrunc,
+	"Copysign": intrinsicCopysign,
+}
+
+// lookupIntrinsic returns the intrinsic operation performed by fn, if any.
+func lookupIntrinsic(fn *ssa.Function) (intrinsicOp, bool) {
+	if fn.Pkg == nil || fn.Pkg.Object.Path() != "math" || fn.Signature.Recv() != nil {
+		return 0, false
+	}
+	op, ok := mathIntrinsics[fn.Name()]
+	return op, ok
+}
+
+// getIntrinsic returns the declaration of the LLVM intrinsic implementing
+// op for floating-point operands of type lltyp.
+func (fr *frame) getIntrinsic(op intrinsicOp, lltyp llvm.Type) llvm.Value {
+	name := intrinsicNames[op] + ".f64"
+	if lltyp.TypeKind() == llvm.FloatTypeKind {
+		name = intrinsicNames[op] + ".f32"
+	}
+	if fn := fr.module.Module.NamedFunction(name); !fn.IsNil() {
+		return fn
+	}
+
+	var params []llvm.Type
+	switch op {
+	case intrinsicCopysign:
+		params = []llvm.Type{lltyp, lltyp}
+	case intrinsicFMulAdd:
+		params = []llvm.Type{lltyp, lltyp, lltyp}
+	default:
+		params = []llvm.Type{lltyp}
+	}
+	fntyp := llvm.FunctionType(lltyp, params, false)
+	return llvm.AddFunction(fr.module.Module, name, fntyp)
+}
+
+// intrinsicCall emits a call to an LLVM intrinsic in place of a call to
+// fn, if fn is one of the functions listed in mathIntrinsics, and returns
+// the results of the call.
+func (fr *frame) intrinsicCall(fn *ssa.Function, args []ssa.Value) ([]*govalue, bool) {
+	op, ok := lookupIntrinsic(fn)
+	if !ok {
+		return nil, false
+	}
+
+	llargs := make([]llvm.Value, len(args))
+	for i, arg := range args {
+		llargs[i] = fr.llvmvalue(arg)
+	}
+	lltyp := llargs[0].Type()
+
+	if op == intrinsicSqrt {
+		// The result of llvm.sqrt is undefined for operands less
+		// than -0, whereas math.Sqrt returns NaN.
+		zero := llvm.ConstFloat(lltyp, 0)
+		nan := llvm.ConstFloat(lltyp, math.NaN())
+		isneg := fr.builder.CreateFCmp(llvm.FloatOLT, llargs[0], zero, "")
+		llargs[0] = fr.builder.CreateSelect(isneg, nan, llargs[0], "")
+	}
+
+	result := fr.builder.CreateCall(fr.getIntrinsic(op, lltyp), llargs, "")
+	typ := fn.Signature.Results().At(0).Type()
+	return []*govalue{newValue(result, typ)}, true
+}
@@ -33,14 +33,63 @@ func (fr *frame) makeMap(typ types.Type, cap_ *govalue) *govalue {
 	return newValue(m[0], typ)
 }
 
-// mapLookup implements v[, ok] = m[k]
-func (fr *frame) mapLookup(m, k *govalue) (v *govalue, ok *govalue) {
+// mapIndexFastPath returns the runtime function specialized for looking
+// up keys of the given type, and the argument to pass it for k, or nil if
+// the generic function must be used. There are specialized functions for
+// 32-bit and 64-bit integer and pointer keys, and for string keys, which
+// avoid the indirect calls to the key type's hash and equality functions.
+func (fr *frame) mapIndexFastPath(keytyp types.Type, k *govalue) (*runtimeFnInfo, llvm.Value) {
+	switch t := keytyp.Underlying().(type) {
+	case *types.Basic:
+		if t.Kind() == types.String {
+			return &fr.runtime.mapIndexFaststr, k.value
+		}
+		if t.Info()&types.IsInteger == 0 && t.Kind() != types.UnsafePointer {
+			return nil, llvm.Value{}
+		}
+	case *types.Pointer, *types.Chan:
+	default:
+		return nil, llvm.Value{}
+	}
+
+	var fn *runtimeFnInfo
+	var inttype llvm.Type
+	switch fr.types.Sizeof(keytyp) {
+	case 4:
+		fn, inttype = &fr.runtime.mapIndexFast32, llvm.Int32Type()
+	case 8:
+		fn, inttype = &fr.runtime.mapIndexFast64, llvm.Int64Type()
+	default:
+		return nil, llvm.Value{}
+	}
+	llk := k.value
+	if llk.Type().TypeKind() == llvm.PointerTypeKind {
+		llk = fr.builder.CreatePtrToInt(llk, inttype, "")
+	}
+	return fn, llk
+}
+
+// mapIndex returns a pointer to the value for the key k in m. If k is not
+// present, the result is null if insert is false, and otherwise points to
+// a newly inserted zero value.
+func (fr *frame) mapIndex(m, k *govalue, insert bool) llvm.Value {
+	keytyp := m.Type().Underlying().(*types.Map).Key()
+	if fn, llk := fr.mapIndexFastPath(keytyp, k); fn != nil {
+		return fn.call(fr, m.value, llk, boolLLVMValue(insert))[0]
+	}
+
 	llk := k.value
 	pk := fr.allocaBuilder.CreateAlloca(llk.Type(), "")
 	fr.builder.CreateStore(llk, pk)
-	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, boolLLVMValue(false))[0]
+	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, boolLLVMValue(insert))[0]
 	valptr.AddInstrAttribute(2, llvm.NoCaptureAttribute)
 	valptr.AddInstrAttribute(2, llvm.ReadOnlyAttribute)
+	return valptr
+}
+
+// mapLookup implements v[, ok] = m[k]
+func (fr *frame) mapLookup(m, k *govalue) (v *govalue, ok *govalue) {
+	valptr := fr.mapIndex(m, k, false)
 	okbit := fr.builder.CreateIsNotNull(valptr, "")
 
 	elemtyp := m.Type().Underlying().(*types.Map).Elem()
@@ -51,12 +100,7 @@ func (fr *frame) mapLookup(m, k *govalue) (v *govalue, ok *govalue) {
 
 // mapUpdate implements m[k] = v
 func (fr *frame) mapUpdate(m, k, v *govalue) {
-	llk := k.value
-	pk := fr.allocaBuilder.CreateAlloca(llk.Type(), "")
-	fr.builder.CreateStore(llk, pk)
-	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, boolLLVMValue(true))[0]
-	valptr.AddInstrAttribute(2, llvm.NoCaptureAttribute)
-	valptr.AddInstrAttribute(2, llvm.ReadOnlyAttribute)
+	valptr := fr.mapIndex(m, k, true)
 
 	elemtyp := m.Type().Underlying().(*types.Map).Elem()
 	llelemtyp := fr.types.ToLLVM(elemtyp)
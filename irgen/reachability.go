@@ -0,0 +1,112 @@
+//===- reachability.go - whole-program method reachability ----------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements the reachability analysis used in whole-program mode
+// to avoid emitting methods that can never be called.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+import (
+	"llvm.org/llgo/third_party/gotools/go/callgraph/rta"
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/types"
+)
+
+// reachability records the functions of the main package that may be called
+// at run time. A nil *reachability treats every function as reachable.
+//
+// Only methods declared in the main package (and the functions nested within
+// them) are ever considered unreachable. Methods of imported types may be
+// called from code we cannot see, and their type descriptors must agree with
+// the ones emitted by other packages, so they are always kept.
+type reachability struct {
+	pkg       *ssa.Package
+	reachable map[*ssa.Function]struct{ AddrTaken bool }
+}
+
+// analyzeReachability performs rapid type analysis over pkg, which must be
+// the main package, rooted at each of its package-level functions.
+//
+// In addition to the exported methods of each runtime type, which RTA
+// already considers reachable via reflection, we assume that reflection may
+// take the address of any value held in an interface (e.g. reflect.New,
+// Value.Addr), so the exported methods of *T are treated as roots for each
+// named runtime type T. The analysis is repeated until no new roots appear.
+func analyzeReachability(pkg *ssa.Package) *reachability {
+	var roots []*ssa.Function
+	for _, m := range pkg.Members {
+		if f, ok := m.(*ssa.Function); ok {
+			roots = append(roots, f)
+		}
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+
+	prog := pkg.Prog
+	for {
+		res := rta.Analyze(roots, false)
+		nroots := len(roots)
+		res.RuntimeTypes.Iterate(func(t types.Type, _ interface{}) {
+			if _, ok := t.(*types.Named); !ok || types.IsInterface(t) {
+				return
+			}
+			mset := prog.MethodSets.MethodSet(types.NewPointer(t))
+			for i := 0; i != mset.Len(); i++ {
+				sel := mset.At(i)
+				if !sel.Obj().Exported() {
+					continue
+				}
+				m := prog.Method(sel)
+				if _, ok := res.Reachable[m]; !ok {
+					roots = append(roots, m)
+				}
+			}
+		})
+		if len(roots) == nroots {
+			return &reachability{pkg: pkg, reachable: res.Reachable}
+		}
+	}
+}
+
+// isMethod reports whether f is a method declared in the main package, or a
+// wrapper for or function nested within such a method.
+func (r *reachability) isMethod(f *ssa.Function) bool {
+	for f.Parent() != nil {
+		f = f.Parent()
+	}
+	obj, ok := f.Object().(*types.Func)
+	if !ok || obj.Pkg() != r.pkg.Object {
+		return false
+	}
+	return obj.Type().(*types.Signature).Recv() != nil
+}
+
+// functionLive reports whether f may be called at run time.
+func (r *reachability) functionLive(f *ssa.Function) bool {
+	if r == nil || !r.isMethod(f) {
+		return true
+	}
+	for f.Parent() != nil {
+		f = f.Parent()
+	}
+	_, ok := r.reachable[f]
+	return ok
+}
+
+// methodLive reports whether the method denoted by sel may be called at run
+// time.
+func (r *reachability) methodLive(sel *types.Selection) bool {
+	if r == nil {
+		return true
+	}
+	return r.functionLive(r.pkg.Prog.Method(sel))
+}
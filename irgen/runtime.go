@@ -52,7 +52,8 @@ func (rfi *runtimeFnInfo) invoke(f *frame, lpad llvm.BasicBlock, args ...llvm.Va
 // runtimeInterface is a struct containing references to
 // runtime types and intrinsic function declarations.
 type runtimeInterface struct {
-	// LLVM intrinsics
+	// LLVM intrinsics and C library functions
+	memcmp,
 	memcpy,
 	memset,
 	returnaddress llvm.Value
@@ -88,6 +89,9 @@ type runtimeInterface struct {
 	mapiterinit,
 	mapiternext,
 	mapIndex,
+	mapIndexFast32,
+	mapIndexFast64,
+	mapIndexFaststr,
 	mapLen,
 	New,
 	newChannel,
@@ -118,6 +122,7 @@ type runtimeInterface struct {
 	sendBig,
 	setDeferRetaddr,
 	strcmp,
+	stringConcat,
 	stringiter2,
 	stringPlus,
 	stringSlice,
@@ -137,11 +142,14 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 	Int := types.Typ[types.Int]
 	Rune := types.Typ[types.Rune]
 	String := types.Typ[types.String]
+	Uint32 := types.Typ[types.Uint32]
+	Uint64 := types.Typ[types.Uint64]
 	Uintptr := types.Typ[types.Uintptr]
 	UnsafePointer := types.Typ[types.UnsafePointer]
 
 	EmptyInterface := types.NewInterface(nil, nil)
 	IntSlice := types.NewSlice(types.Typ[types.Int])
+	StringSlice := types.NewSlice(String)
 
 	for _, rt := range [...]struct {
 		name      string
@@ -294,6 +302,24 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 			args: []types.Type{UnsafePointer, UnsafePointer, Bool},
 			res:  []types.Type{UnsafePointer},
 		},
+		{
+			name: "__go_map_index_fast32",
+			rfi:  &ri.mapIndexFast32,
+			args: []types.Type{UnsafePointer, Uint32, Bool},
+			res:  []types.Type{UnsafePointer},
+		},
+		{
+			name: "__go_map_index_fast64",
+			rfi:  &ri.mapIndexFast64,
+			args: []types.Type{UnsafePointer, Uint64, Bool},
+			res:  []types.Type{UnsafePointer},
+		},
+		{
+			name: "__go_map_index_faststr",
+			rfi:  &ri.mapIndexFaststr,
+			args: []types.Type{UnsafePointer, String, Bool},
+			res:  []types.Type{UnsafePointer},
+		},
 		{
 			name: "__go_map_len",
 			rfi:  &ri.mapLen,
@@ -453,6 +479,12 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 			args: []types.Type{String, String},
 			res:  []types.Type{Int},
 		},
+		{
+			name: "__go_string_concat",
+			rfi:  &ri.stringConcat,
+			args: []types.Type{StringSlice},
+			res:  []types.Type{String},
+		},
 		{
 			name: "__go_string_plus",
 			rfi:  &ri.stringPlus,
@@ -509,6 +541,17 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 	)
 	ri.memset = llvm.AddFunction(module, memsetName, memsetType)
 
+	memcmpType := llvm.FunctionType(
+		llvm.Int32Type(),
+		[]llvm.Type{
+			llvm.PointerType(llvm.Int8Type(), 0),
+			llvm.PointerType(llvm.Int8Type(), 0),
+			tm.target.IntPtrType(),
+		},
+		false,
+	)
+	ri.memcmp = llvm.AddFunction(module, "memcmp", memcmpType)
+
 	memcpyName := "llvm.memcpy.p0i8.p0i8.i" + strconv.Itoa(tm.target.IntPtrType().IntTypeWidth())
 	memcpyType := llvm.FunctionType(
 		llvm.VoidType(),
@@ -19,8 +19,10 @@ import (
 	"go/token"
 	"os"
 	"sort"
+	"strings"
 
 	"llvm.org/llgo/ssaopt"
+	"llvm.org/llgo/third_party/gotools/go/loader"
 	"llvm.org/llgo/third_party/gotools/go/ssa"
 	"llvm.org/llgo/third_party/gotools/go/ssa/ssautil"
 	"llvm.org/llgo/third_party/gotools/go/types"
@@ -101,6 +103,7 @@ func (gi *globalInit) build(typ llvm.Type) llvm.Value {
 type unit struct {
 	*compiler
 	pkg         *ssa.Package
+	pkginfo     *loader.PackageInfo
 	globals     map[ssa.Value]llvm.Value
 	globalInits map[llvm.Value]*globalInit
 
@@ -113,12 +116,17 @@ type unit struct {
 	undefinedFuncs map[*ssa.Function]bool
 
 	gcRoots []llvm.Value
+
+	// initEvaluated is set if the package initializer was evaluated at
+	// compile time.
+	initEvaluated bool
 }
 
-func newUnit(c *compiler, pkg *ssa.Package) *unit {
+func newUnit(c *compiler, pkg *ssa.Package, pkginfo *loader.PackageInfo) *unit {
 	u := &unit{
 		compiler:        c,
 		pkg:             pkg,
+		pkginfo:         pkginfo,
 		globals:         make(map[ssa.Value]llvm.Value),
 		globalInits:     make(map[llvm.Value]*globalInit),
 		funcDescriptors: make(map[*ssa.Function]llvm.Value),
@@ -175,6 +183,7 @@ func (u *unit) translatePackage(pkg *ssa.Package) {
 	// Initialize global storage and type descriptors for this package.
 	// We must create globals regardless of whether they're referenced,
 	// hence the duplication in frame.value.
+	llglobals := make(map[*ssa.Global]llvm.Value)
 	for _, m := range ms {
 		switch v := m.(type) {
 		case *ssa.Global:
@@ -186,6 +195,11 @@ func (u *unit) translatePackage(pkg *ssa.Package) {
 				global.SetLinkage(llvm.InternalLinkage)
 			}
 			u.addGlobal(global, elemtyp)
+			if u.GenerateDebug {
+				name := pkg.Object.Path() + "." + v.Name()
+				u.debug.GlobalVariable(global, name, v.Pos(), elemtyp, !v.Object().Exported())
+			}
+			llglobals[v] = global
 			global = llvm.ConstBitCast(global, u.llvmtypes.ToLLVM(v.Type()))
 			u.globals[v] = global
 		case *ssa.Type:
@@ -193,6 +207,10 @@ func (u *unit) translatePackage(pkg *ssa.Package) {
 		}
 	}
 
+	if u.EvalInit {
+		u.evalPackageInit(llglobals)
+	}
+
 	// Define functions.
 	u.defineFunctionsInOrder(ssautil.AllFunctions(pkg.Prog))
 
@@ -299,6 +317,11 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		return
 	}
 
+	// In whole-program mode, skip methods that can never be called.
+	if !u.reachability.functionLive(f) {
+		return
+	}
+
 	llfn := u.resolveFunctionGlobal(f)
 	linkage := u.getFunctionLinkage(f)
 
@@ -316,6 +339,9 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		return
 	}
 
+	if u.inlining() {
+		ssaopt.Inline(f, u.InlineThreshold)
+	}
 	ssaopt.LowerAllocsToStack(f)
 
 	if u.DumpSSA {
@@ -334,11 +360,16 @@ func (u *unit) defineFunction(f *ssa.Function) {
 
 	// Push the compile unit and function onto the debug context.
 	if u.GenerateDebug {
-		u.debug.PushFunction(fr.function, f.Signature, f.Pos())
+		u.debug.PushFunction(fr.function, u.debugFunctionName(f), f.Signature, f.Pos(), f.Syntax())
 		defer u.debug.PopFunction()
 		u.debug.SetLocation(fr.builder, f.Pos())
 	}
 
+	if u.initEvaluated && f == u.pkg.Func("init") {
+		fr.defineEvaluatedInit()
+		return
+	}
+
 	// If a function calls recover, we create a separate function to
 	// hold the real function, and this function calls __go_can_recover
 	// and bridges to it.
@@ -366,6 +397,11 @@ func (u *unit) defineFunction(f *ssa.Function) {
 			}
 		}
 		fr.env[param] = newValue(llparam, param.Type())
+		if u.GenerateDebug {
+			if v, ok := param.Object().(*types.Var); ok && v.Name() != "_" {
+				u.debug.Value(fr.builder, v, llparam, i, false)
+			}
+		}
 	}
 
 	// Load closure, extract free vars.
@@ -386,6 +422,16 @@ func (u *unit) defineFunction(f *ssa.Function) {
 			ptr = fr.builder.CreateLoad(ptr, "")
 			fr.env[fv] = newValue(ptr, fv.Type())
 		}
+		if u.GenerateDebug {
+			chain := fr.function.Param(fti.chainIndex)
+			for i, v := range fr.capturedVars(f) {
+				if v != nil {
+					offset := u.target.ElementOffset(structType, i+1)
+					byRef := !types.Identical(v.Type(), f.FreeVars[i].Type())
+					u.debug.CapturedVariable(fr.builder, v, chain, offset, byRef)
+				}
+			}
+		}
 	}
 
 	// Allocate stack space for locals in the prologue block.
@@ -396,6 +442,9 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		bcalloca := fr.builder.CreateBitCast(alloca, llvm.PointerType(llvm.Int8Type(), 0), "")
 		value := newValue(bcalloca, local.Type())
 		fr.env[local] = value
+		if u.GenerateDebug {
+			fr.declareLocal(local, alloca)
+		}
 	}
 
 	// If the function contains any defers, we must first create
@@ -404,6 +453,7 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	if f.Recover != nil || hasDefer(f) {
 		fr.unwindBlock = llvm.AddBasicBlock(fr.function, "")
 		fr.frameptr = fr.builder.CreateAlloca(llvm.Int8Type(), "")
+		fr.setupOpenDefer(f)
 	}
 
 	// Keep track of the block into which we need to insert the call
@@ -459,12 +509,14 @@ type frame struct {
 	runtimeErrorBlocks     [gccgoRuntimeErrorCount]llvm.BasicBlock
 	unwindBlock            llvm.BasicBlock
 	frameptr               llvm.Value
+	openDefer              *openDefer
 	env                    map[ssa.Value]*govalue
 	ptr                    map[ssa.Value]llvm.Value
 	tuples                 map[ssa.Value][]*govalue
 	phis                   []pendingPhi
 	canRecover             llvm.Value
 	isInit                 bool
+	staticAllocs           int
 }
 
 func newFrame(u *unit, fn llvm.Value) *frame {
@@ -860,6 +912,125 @@ func (fr *frame) maybeStoreInInitializer(val, addr llvm.Value) bool {
 	}
 }
 
+// debugVar returns the local variable referred to by instr, or nil if
+// instr refers to something other than a local variable of the function
+// being translated.
+func (fr *frame) debugVar(instr *ssa.DebugRef) *types.Var {
+	id, ok := instr.Expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, ok := fr.pkginfo.ObjectOf(id).(*types.Var)
+	if !ok || v.IsField() || v.Parent() == fr.pkg.Object.Scope() || v.Name() == "_" {
+		return nil
+	}
+	// Variables captured by a closure are described relative to the
+	// closure's context; see capturedVars.
+	if _, ok := instr.X.(*ssa.FreeVar); ok {
+		return nil
+	}
+	return v
+}
+
+// debugFunctionName returns the name by which f is known in debug info.
+// Function literals are named after their enclosing function, as in
+// "pkg.outer.func1", and the wrappers of method values after their
+// method, as in "pkg.(*T).M-fm". Other functions are known by their
+// symbol names.
+func (u *unit) debugFunctionName(f *ssa.Function) string {
+	if parent := f.Parent(); parent != nil {
+		// Function literals are named parent$N by the SSA builder.
+		index := f.Name()[strings.LastIndex(f.Name(), "$")+1:]
+		if parent.Parent() == nil {
+			index = "func" + index
+		}
+		return u.debugFunctionName(parent) + "." + index
+	}
+	if obj, ok := f.Object().(*types.Func); ok && strings.HasSuffix(f.Name(), "$bound") {
+		recv := obj.Type().(*types.Signature).Recv().Type()
+		var recvName string
+		if ptr, ok := recv.(*types.Pointer); ok {
+			recvName = "(*" + types.TypeString(obj.Pkg(), ptr.Elem()) + ")"
+		} else {
+			recvName = types.TypeString(obj.Pkg(), recv)
+		}
+		name := recvName + "." + obj.Name() + "-fm"
+		if obj.Pkg() != nil {
+			name = ManglePackagePath(obj.Pkg().Path()) + "." + name
+		}
+		return name
+	}
+	return u.resolveFunctionGlobal(f).Name()
+}
+
+// capturedVars returns the variables captured by the free variables of
+// the closure f, indexed as f.FreeVars. Captured source variables are
+// identified by their declaring positions, as recorded by the debug
+// references to them in f; the receiver of a method value is described
+// by a synthetic variable. An element is nil if the variable is unknown.
+func (fr *frame) capturedVars(f *ssa.Function) []*types.Var {
+	declared := make(map[token.Pos]*types.Var)
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if ref, ok := instr.(*ssa.DebugRef); ok {
+				if id, ok := ref.Expr.(*ast.Ident); ok {
+					if v, ok := fr.pkginfo.ObjectOf(id).(*types.Var); ok && v.Pos().IsValid() {
+						declared[v.Pos()] = v
+					}
+				}
+			}
+		}
+	}
+	vars := make([]*types.Var, len(f.FreeVars))
+	for i, fv := range f.FreeVars {
+		if v := declared[fv.Pos()]; v != nil && v.Name() == fv.Name() {
+			vars[i] = v
+		} else if f.Synthetic != "" {
+			vars[i] = types.NewVar(fv.Pos(), fr.pkg.Object, fv.Name(), fv.Type())
+		}
+	}
+	return vars
+}
+
+// declareLocal emits an llvm.dbg.declare call for the stack-allocated
+// local, if it is the storage of a source variable.
+func (fr *frame) declareLocal(local *ssa.Alloc, alloca llvm.Value) {
+	for _, ref := range *local.Referrers() {
+		if ref, ok := ref.(*ssa.DebugRef); ok && ref.IsAddr {
+			if v := fr.debugVar(ref); v != nil {
+				fr.debug.Declare(fr.builder, v, alloca, -1)
+				return
+			}
+		}
+	}
+}
+
+// debugRef emits an llvm.dbg.value call associating the local variable
+// referred to by instr with its current value. Variables whose storage
+// is on the stack are described once, by declareLocal.
+func (fr *frame) debugRef(instr *ssa.DebugRef) {
+	v := fr.debugVar(instr)
+	if v == nil {
+		return
+	}
+	switch x := instr.X.(type) {
+	case *ssa.Alloc:
+		if !x.Heap {
+			return
+		}
+	case *ssa.Parameter:
+		if x.Object() == v {
+			return
+		}
+	case ssa.Instruction:
+		// Values folded into their uses have no representation.
+		if _, ok := fr.env[instr.X]; !ok {
+			return
+		}
+	}
+	fr.debug.Value(fr.builder, v, fr.llvmvalue(instr.X), -1, instr.IsAddr)
+}
+
 func (fr *frame) instruction(instr ssa.Instruction) {
 	fr.logf("[%T] %v @ %s\n", instr, instr, fr.pkg.Prog.Fset.Position(instr.Pos()))
 	if fr.GenerateDebug {
@@ -882,6 +1053,13 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 			global := llvm.AddGlobal(fr.module.Module, llvmtyp, "")
 			global.SetLinkage(llvm.InternalLinkage)
 			fr.addGlobal(global, typ)
+			// Number the variables, as many allocations share
+			// a comment such as "complit".
+			fr.staticAllocs++
+			if fr.GenerateDebug && instr.Comment != "" {
+				name := fmt.Sprintf("%s.init.%s.%d", fr.pkg.Object.Path(), instr.Comment, fr.staticAllocs)
+				fr.debug.GlobalVariable(global, name, instr.Pos(), typ, true)
+			}
 			ptr := llvm.ConstBitCast(global, llvm.PointerType(llvm.Int8Type(), 0))
 			fr.env[instr] = newValue(ptr, instr.Type())
 		} else {
@@ -892,6 +1070,28 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		}
 
 	case *ssa.BinOp:
+		if instr.Op == token.ADD && isString(instr.Type()) {
+			if isFusedConcat(instr) {
+				break
+			}
+			if operands := concatOperands(instr); len(operands) > 2 {
+				strs := make([]*govalue, len(operands))
+				for i, operand := range operands {
+					strs[i] = fr.value(operand)
+				}
+				fr.env[instr] = fr.concatenateStringList(strs)
+				break
+			}
+		}
+		if fr.FPContract != FPContractOff && isFloat(instr.Type()) {
+			if fr.isContractedMul(instr) {
+				break
+			}
+			if mul := contractibleMul(instr); mul != nil && fr.isContractedMul(mul) {
+				fr.env[instr] = fr.mulAdd(instr, mul)
+				break
+			}
+		}
 		lhs, rhs := fr.value(instr.X), fr.value(instr.Y)
 		fr.env[instr] = fr.binaryOp(lhs, instr.Op, rhs)
 
@@ -923,9 +1123,20 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 
 	case *ssa.Convert:
 		v := fr.value(instr.X)
+		if isTransientString(instr) {
+			fr.env[instr] = fr.byteSliceToStringNoCopy(v)
+			break
+		}
 		fr.env[instr] = fr.convert(v, instr.Type())
 
+	case *ssa.DebugRef:
+		fr.debugRef(instr)
+
 	case *ssa.Defer:
+		if fr.openDefer != nil && fr.openDefer.instr == instr {
+			fr.deferOpen()
+			break
+		}
 		fn, arg := fr.createThunk(instr)
 		fr.runtime.Defer.call(fr, fr.frameptr, fn, arg)
 
@@ -1145,7 +1356,11 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		fr.retInf.encode(llvm.GlobalContext(), fr.allocaBuilder, fr.builder, vals)
 
 	case *ssa.RunDefers:
-		fr.runDefers()
+		if fr.openDefer != nil {
+			fr.runOpenDefer()
+		} else {
+			fr.runDefers()
+		}
 
 	case *ssa.Select:
 		index, recvOk, recvElems := fr.chanSelect(instr)
@@ -1178,6 +1393,9 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 	case *switchInstr:
 		fr.emitSwitch(instr)
 
+	case *typeSwitchInstr:
+		fr.emitTypeSwitch(instr)
+
 	case *ssa.TypeAssert:
 		x := fr.value(instr.X)
 		if instr.CommaOk {
@@ -1284,6 +1502,14 @@ func (fr *frame) callInstruction(instr ssa.CallInstruction) []*govalue {
 		}
 		return fr.callBuiltin(typ, builtin, call.Args)
 	}
+	if ssafn, ok := call.Value.(*ssa.Function); ok {
+		if results, ok := fr.atomicCall(ssafn, call.Args); ok {
+			return results
+		}
+		if results, ok := fr.intrinsicCall(ssafn, call.Args); ok {
+			return results
+		}
+	}
 
 	args := make([]*govalue, len(call.Args))
 	for i, arg := range call.Args {
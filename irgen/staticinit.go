@@ -0,0 +1,209 @@
+//===- staticinit.go - compile-time package initialization ----------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements compile-time evaluation of package initializers.
+//
+//===----------------------------------------------------------------------===//
+
+package irgen
+
+import (
+	"fmt"
+
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/ssa/interp"
+	"llvm.org/llgo/third_party/gotools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// initEvalMaxSteps limits the number of instructions interpreted when
+// evaluating a package initializer. If it is exceeded, the initializer is
+// run at program start instead. A step limit, unlike a time limit, makes
+// the outcome independent of the speed of the machine compiling.
+const initEvalMaxSteps = 1000000
+
+// evalPackageInit attempts to evaluate the package's init function at
+// compile time, given the LLVM globals for each of the package's globals.
+// If evaluation succeeds, the globals are given static initializers and
+// the init function is reduced to its prologue.
+func (u *unit) evalPackageInit(llglobals map[*ssa.Global]llvm.Value) {
+	limits := interp.EvalLimits{MaxSteps: initEvalMaxSteps}
+	res, err := interp.EvalInit(u.pkg, u.llvmtypes, limits)
+	if err != nil {
+		u.logf("Not evaluating init at compile time: %v", err)
+		return
+	}
+
+	b := staticInitBuilder{unit: u, objects: make(map[*interp.Object]llvm.Value)}
+	for g, obj := range res.Globals {
+		b.objects[obj] = llglobals[g]
+	}
+	for _, obj := range res.Objects {
+		global := b.object(obj)
+		init := b.value(obj.Value, obj.Type)
+		u.globalInits[global].update(global.Type().ElementType(), nil, init)
+	}
+	u.initEvaluated = true
+	u.logf("Evaluated init at compile time")
+}
+
+// staticInitBuilder builds LLVM constants for the values produced by
+// interp.EvalInit.
+type staticInitBuilder struct {
+	*unit
+	objects map[*interp.Object]llvm.Value
+}
+
+// object returns the LLVM global for obj, creating an anonymous global if
+// obj does not represent a package global.
+func (b *staticInitBuilder) object(obj *interp.Object) llvm.Value {
+	if global, ok := b.objects[obj]; ok {
+		return global
+	}
+	global := llvm.AddGlobal(b.module.Module, b.llvmtypes.ToLLVM(obj.Type), "")
+	global.SetLinkage(llvm.InternalLinkage)
+	b.addGlobal(global, obj.Type)
+	b.objects[obj] = global
+	return global
+}
+
+// pointer returns an i8* constant for p.
+func (b *staticInitBuilder) pointer(p interp.Pointer) llvm.Value {
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	if p.Obj == nil {
+		return llvm.ConstNull(i8ptr)
+	}
+	indices := make([]llvm.Value, len(p.Path)+1)
+	indices[0] = llvm.ConstNull(llvm.Int32Type())
+	for i, index := range p.Path {
+		indices[i+1] = llvm.ConstInt(llvm.Int32Type(), uint64(index), false)
+	}
+	ptr := llvm.ConstGEP(b.object(p.Obj), indices)
+	return llvm.ConstBitCast(ptr, i8ptr)
+}
+
+// value returns a constant for v, of type typ.
+func (b *staticInitBuilder) value(v interp.Value, typ types.Type) llvm.Value {
+	lltyp := b.llvmtypes.ToLLVM(typ)
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		switch v := v.(type) {
+		case bool:
+			return boolLLVMValue(v)
+		case string:
+			return b.constString(v)
+		case float32:
+			return llvm.ConstFloat(lltyp, float64(v))
+		case float64:
+			return llvm.ConstFloat(lltyp, v)
+		case complex64:
+			floattyp := lltyp.StructElementTypes()[0]
+			return llvm.ConstStruct([]llvm.Value{
+				llvm.ConstFloat(floattyp, float64(real(v))),
+				llvm.ConstFloat(floattyp, float64(imag(v))),
+			}, false)
+		case complex128:
+			floattyp := lltyp.StructElementTypes()[0]
+			return llvm.ConstStruct([]llvm.Value{
+				llvm.ConstFloat(floattyp, real(v)),
+				llvm.ConstFloat(floattyp, imag(v)),
+			}, false)
+		default:
+			return llvm.ConstInt(lltyp, constIntBits(v), !isUnsigned(t))
+		}
+
+	case *types.Struct:
+		agg := v.(interp.Aggregate)
+		fields := make([]llvm.Value, len(agg))
+		for i, f := range agg {
+			fields[i] = b.value(f, t.Field(i).Type())
+		}
+		return llvm.ConstStruct(fields, false)
+
+	case *types.Array:
+		agg := v.(interp.Aggregate)
+		elems := make([]llvm.Value, len(agg))
+		for i, e := range agg {
+			elems[i] = b.value(e, t.Elem())
+		}
+		return llvm.ConstArray(b.llvmtypes.ToLLVM(t.Elem()), elems)
+
+	case *types.Pointer:
+		return b.pointer(v.(interp.Pointer))
+
+	case *types.Slice:
+		s := v.(interp.Slice)
+		return llvm.ConstStruct([]llvm.Value{
+			b.pointer(s.Ptr),
+			llvm.ConstInt(b.types.inttype, uint64(s.Len), false),
+			llvm.ConstInt(b.types.inttype, uint64(s.Cap), false),
+		}, false)
+
+	case *types.Interface:
+		iv := v.(interp.Interface)
+		if iv.Type == nil {
+			return llvm.ConstNull(lltyp)
+		}
+		return llvm.ConstStruct([]llvm.Value{
+			b.types.getItabPointer(iv.Type, t),
+			b.pointer(iv.Data),
+		}, false)
+
+	case *types.Signature:
+		if f := v.(*ssa.Function); f != nil {
+			return b.resolveFunctionDescriptor(f).value
+		}
+		return llvm.ConstNull(lltyp)
+
+	case *types.Map, *types.Chan:
+		// Maps and channels are always nil.
+		return llvm.ConstNull(lltyp)
+	}
+	panic(fmt.Sprintf("unhandled type: %s", typ))
+}
+
+// constIntBits returns the bits of the integer v, sign extended to 64 bits.
+func constIntBits(v interp.Value) uint64 {
+	switch v := v.(type) {
+	case int:
+		return uint64(v)
+	case int8:
+		return uint64(v)
+	case int16:
+		return uint64(v)
+	case int32:
+		return uint64(v)
+	case int64:
+		return uint64(v)
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	case uintptr:
+		return uint64(v)
+	}
+	panic(fmt.Sprintf("unexpected integer value: %T", v))
+}
+
+// defineEvaluatedInit defines the body of an init function whose effects
+// were computed by evalPackageInit: only the init prologue is emitted.
+func (fr *frame) defineEvaluatedInit() {
+	entry := llvm.AddBasicBlock(fr.function, "")
+	fr.builder.SetInsertPointAtEnd(entry)
+	registerGcBlock := fr.emitInitPrologue()
+	fr.builder.CreateRetVoid()
+	fr.builder.SetInsertPointBefore(registerGcBlock.FirstInstruction())
+	fr.registerGcRoots()
+}
@@ -16,6 +16,7 @@ package irgen
 import (
 	"go/token"
 
+	"llvm.org/llgo/third_party/gotools/go/ssa"
 	"llvm.org/llgo/third_party/gotools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
@@ -25,6 +26,144 @@ func (fr *frame) concatenateStrings(lhs, rhs *govalue) *govalue {
 	return newValue(result[0], types.Typ[types.String])
 }
 
+// isFusedConcat reports whether v is a string concatenation whose only
+// use is as an operand of another concatenation in the same block. Such a
+// concatenation is not emitted itself; its operands are instead added to
+// those of the concatenation that uses it.
+//
+// Concatenations are not fused across blocks, so a string built up with
+// += in a loop is still copied on each iteration, although the operands
+// added by each iteration are concatenated with a single call.
+func isFusedConcat(v *ssa.BinOp) bool {
+	if v.Op != token.ADD || !isString(v.Type()) {
+		return false
+	}
+	refs := valueReferrers(v)
+	if len(refs) != 1 {
+		return false
+	}
+	user, ok := refs[0].(*ssa.BinOp)
+	return ok && user.Op == token.ADD && user.Block() == v.Block() && isString(user.Type())
+}
+
+// concatOperands returns the operands of the chain of string
+// concatenations ending in v, in order.
+func concatOperands(v *ssa.BinOp) []ssa.Value {
+	var operands []ssa.Value
+	for _, x := range [...]ssa.Value{v.X, v.Y} {
+		if x, ok := x.(*ssa.BinOp); ok && isFusedConcat(x) {
+			operands = append(operands, concatOperands(x)...)
+		} else {
+			operands = append(operands, x)
+		}
+	}
+	return operands
+}
+
+// concatenateStringList concatenates the given strings with a single call
+// to the runtime, which allocates the result once.
+func (fr *frame) concatenateStringList(strs []*govalue) *govalue {
+	if len(strs) == 2 {
+		return fr.concatenateStrings(strs[0], strs[1])
+	}
+
+	stringType := fr.types.ToLLVM(types.Typ[types.String])
+	array := fr.allocaBuilder.CreateAlloca(llvm.ArrayType(stringType, len(strs)), "")
+	for i, str := range strs {
+		ptr := fr.builder.CreateStructGEP(array, i, "")
+		fr.builder.CreateStore(str.value, ptr)
+	}
+
+	sliceType := fr.types.ToLLVM(types.NewSlice(types.Typ[types.String]))
+	data := fr.builder.CreateBitCast(array, sliceType.StructElementTypes()[0], "")
+	n := llvm.ConstInt(fr.types.inttype, uint64(len(strs)), false)
+	slice := llvm.Undef(sliceType)
+	slice = fr.builder.CreateInsertValue(slice, data, 0, "")
+	slice = fr.builder.CreateInsertValue(slice, n, 1, "")
+	slice = fr.builder.CreateInsertValue(slice, n, 2, "")
+
+	result := fr.runtime.stringConcat.call(fr, slice)
+	return newValue(result[0], types.Typ[types.String])
+}
+
+// isTransientString reports whether the result of the []byte to string
+// conversion c is only used as a map key in lookups, or as an operand of
+// string comparisons, before any instruction that may modify the bytes.
+// Such a string does not outlive the byte slice's current contents, so
+// the conversion need not copy them.
+func isTransientString(c *ssa.Convert) bool {
+	if !isString(c.Type()) || !isSlice(c.X.Type(), types.Byte) {
+		return false
+	}
+
+	refs := *c.Referrers()
+	if len(refs) == 0 {
+		return false
+	}
+	pending := make(map[ssa.Instruction]bool)
+	for _, ref := range refs {
+		if ref.Block() != c.Block() {
+			return false
+		}
+		switch ref := ref.(type) {
+		case *ssa.Lookup:
+			if _, ok := ref.X.Type().Underlying().(*types.Map); !ok || ref.X == c {
+				return false
+			}
+		case *ssa.BinOp:
+			switch ref.Op {
+			case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			default:
+				return false
+			}
+		case *ssa.DebugRef:
+		default:
+			return false
+		}
+		pending[ref] = true
+	}
+
+	// Check that nothing between the conversion and its last use may
+	// write to memory or call a function that does.
+	instrs := c.Block().Instrs
+	i := 0
+	for instrs[i] != c {
+		i++
+	}
+	for _, instr := range instrs[i+1:] {
+		if len(pending) == 0 {
+			break
+		}
+		if pending[instr] {
+			delete(pending, instr)
+			continue
+		}
+		switch instr := instr.(type) {
+		case *ssa.BinOp, *ssa.ChangeType, *ssa.Convert, *ssa.DebugRef,
+			*ssa.Extract, *ssa.Field, *ssa.FieldAddr, *ssa.Index,
+			*ssa.IndexAddr, *ssa.Lookup:
+		case *ssa.UnOp:
+			if instr.Op == token.ARROW {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return len(pending) == 0
+}
+
+// byteSliceToStringNoCopy converts the []byte v to a string which shares
+// its storage.
+func (fr *frame) byteSliceToStringNoCopy(v *govalue) *govalue {
+	data := fr.builder.CreateExtractValue(v.value, 0, "")
+	len := fr.builder.CreateExtractValue(v.value, 1, "")
+	struct_ := llvm.Undef(fr.types.ToLLVM(types.Typ[types.String]))
+	struct_ = fr.builder.CreateInsertValue(struct_, data, 0, "")
+	struct_ = fr.builder.CreateInsertValue(struct_, len, 1, "")
+	return newValue(struct_, types.Typ[types.String])
+}
+
 func (fr *frame) compareStringEmpty(v llvm.Value) *govalue {
 	len := fr.builder.CreateExtractValue(v, 1, "")
 	result := fr.builder.CreateIsNull(len, "")
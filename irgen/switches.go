@@ -15,10 +15,12 @@ package irgen
 
 import (
 	"go/token"
+	"sort"
 
 	"llvm.org/llgo/third_party/gotools/go/exact"
 	"llvm.org/llgo/third_party/gotools/go/ssa"
 	"llvm.org/llgo/third_party/gotools/go/ssa/ssautil"
+	"llvm.org/llgo/third_party/gotools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
 
@@ -49,8 +51,17 @@ func (sw *switchInstr) Pos() token.Pos {
 	return token.NoPos
 }
 
+// minStringSwitchCases is the minimum number of distinct cases for which
+// a switch on a string is lowered to a dispatch on its length and first
+// byte, rather than a chain of comparisons.
+const minStringSwitchCases = 4
+
 // emitSwitch emits an LLVM switch instruction.
 func (fr *frame) emitSwitch(instr *switchInstr) {
+	if isString(instr.X.Type()) {
+		fr.emitStringSwitch(instr)
+		return
+	}
 	cases, _ := dedupConstCases(fr, instr.ConstCases)
 	ncases := len(cases)
 	elseblock := fr.block(instr.Default)
@@ -60,17 +71,120 @@ func (fr *frame) emitSwitch(instr *switchInstr) {
 	}
 }
 
+// emitStringSwitch emits the code for a switch on a string. The cases are
+// grouped by length, and then by first byte, each of which is dispatched on
+// with an LLVM switch instruction; the cases in each group are then checked
+// in order with memcmp. The index of the matching case selects the body in
+// a final LLVM switch, which is the only predecessor of the case bodies.
+func (fr *frame) emitStringSwitch(instr *switchInstr) {
+	cases, _ := dedupConstCases(fr, instr.ConstCases)
+	x := fr.llvmvalue(instr.X)
+	xdata := fr.builder.CreateExtractValue(x, 0, "")
+	xlen := fr.builder.CreateExtractValue(x, 1, "")
+
+	// Group the cases by length and first byte, preserving their order.
+	type caseInfo struct {
+		index int
+		value string
+	}
+	var lengths []int
+	bylen := make(map[int]map[byte][]caseInfo)
+	for i, c := range cases {
+		s := exact.StringVal(c.Value.Value)
+		if bylen[len(s)] == nil {
+			bylen[len(s)] = make(map[byte][]caseInfo)
+			lengths = append(lengths, len(s))
+		}
+		var first byte
+		if len(s) != 0 {
+			first = s[0]
+		}
+		bylen[len(s)][first] = append(bylen[len(s)][first], caseInfo{i, s})
+	}
+	sort.Ints(lengths)
+
+	donebb := llvm.AddBasicBlock(fr.function, "")
+	nomatchbb := llvm.AddBasicBlock(fr.function, "")
+	var indices []llvm.Value
+	var preds []llvm.BasicBlock
+	match := func(index int) {
+		indices = append(indices, llvm.ConstInt(llvm.Int32Type(), uint64(index), true))
+		preds = append(preds, fr.builder.GetInsertBlock())
+	}
+
+	lenswitch := fr.builder.CreateSwitch(xlen, nomatchbb, len(lengths))
+	for _, n := range lengths {
+		lenbb := llvm.AddBasicBlock(fr.function, "")
+		lenswitch.AddCase(llvm.ConstInt(fr.types.inttype, uint64(n), false), lenbb)
+		fr.builder.SetInsertPointAtEnd(lenbb)
+		if n == 0 {
+			// There is at most one case for the empty string.
+			match(bylen[0][0][0].index)
+			fr.builder.CreateBr(donebb)
+			continue
+		}
+
+		var firsts []int
+		for first := range bylen[n] {
+			firsts = append(firsts, int(first))
+		}
+		sort.Ints(firsts)
+		first := fr.builder.CreateLoad(xdata, "")
+		byteswitch := fr.builder.CreateSwitch(first, nomatchbb, len(firsts))
+		for _, b := range firsts {
+			bytebb := llvm.AddBasicBlock(fr.function, "")
+			byteswitch.AddCase(llvm.ConstInt(llvm.Int8Type(), uint64(b), false), bytebb)
+			fr.builder.SetInsertPointAtEnd(bytebb)
+			for _, c := range bylen[n][byte(b)] {
+				cdata := fr.builder.CreateExtractValue(fr.constString(c.value), 0, "")
+				size := llvm.ConstInt(fr.target.IntPtrType(), uint64(n), false)
+				cmp := fr.builder.CreateCall(fr.runtime.memcmp, []llvm.Value{xdata, cdata, size}, "")
+				eq := fr.builder.CreateIsNull(cmp, "")
+				nextbb := llvm.AddBasicBlock(fr.function, "")
+				match(c.index)
+				fr.builder.CreateCondBr(eq, donebb, nextbb)
+				fr.builder.SetInsertPointAtEnd(nextbb)
+			}
+			fr.builder.CreateBr(nomatchbb)
+		}
+	}
+
+	fr.builder.SetInsertPointAtEnd(nomatchbb)
+	match(-1)
+	fr.builder.CreateBr(donebb)
+
+	fr.builder.SetInsertPointAtEnd(donebb)
+	index := fr.builder.CreatePHI(llvm.Int32Type(), "")
+	index.AddIncoming(indices, preds)
+	llswitch := fr.builder.CreateSwitch(index, fr.block(instr.Default), len(cases))
+	for i, c := range cases {
+		llswitch.AddCase(llvm.ConstInt(llvm.Int32Type(), uint64(i), false), fr.block(c.Body))
+	}
+}
+
+// isSwitchable reports whether the switch sw is lowered to an LLVM switch
+// instruction or, for strings, a dispatch built from them.
+func isSwitchable(fr *frame, sw ssautil.Switch) bool {
+	switch t := sw.X.Type(); {
+	case isInteger(t), isBoolean(t):
+		return true
+	case isString(t):
+		cases, _ := dedupConstCases(fr, sw.ConstCases)
+		return len(cases) >= minStringSwitchCases
+	}
+	return false
+}
+
 // transformSwitches replaces the final If statement in start blocks
 // with a high-level switch instruction, and erases chained condition
 // blocks.
 func (fr *frame) transformSwitches(f *ssa.Function) {
 	for _, sw := range ssautil.Switches(f) {
 		if sw.ConstCases == nil {
-			// TODO(axw) investigate switch
-			// on hashes in type switches.
+			fr.transformTypeSwitch(sw)
 			continue
 		}
-		if !isInteger(sw.X.Type()) && !isBoolean(sw.X.Type()) {
+		if !isSwitchable(fr, sw) {
 			// LLVM switches can only operate on integers.
 			continue
 		}
@@ -95,31 +209,40 @@ func (fr *frame) transformSwitches(f *ssa.Function) {
 		}
 
 		// Remove redundant edges corresponding to duplicate cases
-		// that will not feature in the LLVM switch instruction.
+		// that will not feature in the LLVM switch instruction. The
+		// else edge of a duplicate is kept, as it may lead to the
+		// default block.
 		for _, c := range duplicates {
-			for _, succ := range c.Block.Succs {
-				for i, pred := range succ.Preds {
-					if pred == c.Block {
-						head := succ.Preds[:i]
-						tail := succ.Preds[i+1:]
-						succ.Preds = append(head, tail...)
-						removePhiEdge(succ, i)
-						break
-					}
+			succ := c.Body
+			for i, pred := range succ.Preds {
+				if pred == c.Block {
+					head := succ.Preds[:i]
+					tail := succ.Preds[i+1:]
+					succ.Preds = append(head, tail...)
+					removePhiEdge(succ, i)
+					break
+				}
+			}
+			succ = c.Block.Succs[1]
+			for i, pred := range succ.Preds {
+				if pred == c.Block {
+					succ.Preds[i] = sw.Start
+					break
 				}
 			}
 		}
 	}
 }
 
-// dedupConstCases separates duplicate const cases.
+// dedupConstCases separates duplicate const cases. As the comparisons
+// are made in order, the first of a set of duplicate cases is kept.
 //
 // TODO(axw) fix this in go/ssa/ssautil.
 func dedupConstCases(fr *frame, in []ssautil.ConstCase) (unique, duplicates []ssautil.ConstCase) {
 	unique = make([]ssautil.ConstCase, 0, len(in))
 dedup:
 	for i, c1 := range in {
-		for _, c2 := range in[i+1:] {
+		for _, c2 := range in[:i] {
 			if exact.Compare(c1.Value.Value, token.EQL, c2.Value.Value) {
 				duplicates = append(duplicates, c1)
 				continue dedup
@@ -143,3 +266,181 @@ func removePhiEdge(bb *ssa.BasicBlock, i int) {
 		instr.Edges = append(head, tail...)
 	}
 }
+
+// typeSwitchInstr is an instruction representing a switch on the
+// dynamic type of an interface value, dispatched on type hashes.
+type typeSwitchInstr struct {
+	ssa.Instruction
+	ssautil.Switch
+}
+
+func (sw *typeSwitchInstr) String() string {
+	return sw.Switch.String()
+}
+
+func (sw *typeSwitchInstr) Parent() *ssa.Function {
+	return sw.Start.Parent()
+}
+
+func (sw *typeSwitchInstr) Block() *ssa.BasicBlock {
+	return sw.Start
+}
+
+func (sw *typeSwitchInstr) Operands(rands []*ssa.Value) []*ssa.Value {
+	return nil
+}
+
+func (sw *typeSwitchInstr) Pos() token.Pos {
+	return token.NoPos
+}
+
+// minTypeSwitchCases is the minimum number of distinct concrete types
+// for which a type switch is lowered to a dispatch on type hashes,
+// rather than a chain of type checks.
+const minTypeSwitchCases = 4
+
+// transformTypeSwitch replaces the type assertion and If statement at
+// the end of the start block of a type switch with a typeSwitchInstr,
+// and erases the chained type assertion blocks.
+//
+// Only the leading cases with concrete types are lowered, as interface
+// types must be checked against the method set; the first case with an
+// interface type becomes the default of the lowered switch.
+func (fr *frame) transformTypeSwitch(sw ssautil.Switch) {
+	for i, c := range sw.TypeCases {
+		if _, ok := c.Type.Underlying().(*types.Interface); ok {
+			sw.Default = c.Block
+			sw.TypeCases = sw.TypeCases[:i]
+			break
+		}
+	}
+	cases, duplicates := dedupTypeCases(sw.TypeCases)
+	if len(cases) < minTypeSwitchCases {
+		return
+	}
+
+	// The start block must end with exactly the type assertion,
+	// its extracts and the If statement.
+	n := len(sw.Start.Instrs)
+	ta, ok := sw.Start.Instrs[n-4].(*ssa.TypeAssert)
+	if !ok {
+		return
+	}
+	if ext, ok := sw.Start.Instrs[n-2].(*ssa.Extract); !ok || ext.Tuple != ta {
+		return
+	}
+
+	instr := &typeSwitchInstr{Switch: sw}
+	sw.Start.Instrs = append(sw.Start.Instrs[:n-4], instr)
+	for _, c := range sw.TypeCases[1:] {
+		fr.blocks[c.Block.Index].EraseFromParent()
+		fr.blocks[c.Block.Index] = llvm.BasicBlock{}
+	}
+
+	// Remove the edges corresponding to duplicate cases, which
+	// will never be taken.
+	for _, c := range duplicates {
+		for i, pred := range c.Body.Preds {
+			if pred == c.Block {
+				head := c.Body.Preds[:i]
+				tail := c.Body.Preds[i+1:]
+				c.Body.Preds = append(head, tail...)
+				removePhiEdge(c.Body, i)
+				break
+			}
+		}
+	}
+}
+
+// emitTypeSwitch emits the code for a type switch. The hash is loaded
+// from the type descriptor of the operand and dispatched on with an
+// LLVM switch instruction; the descriptor is then compared with that of
+// each case type with a matching hash, in order.
+//
+// Each case body is entered from a block which binds the case value, and
+// which stands in for the erased type assertion block of the case for
+// the purposes of fixupPhis. Likewise, the block reached when no case
+// matches stands in for the last type assertion block.
+func (fr *frame) emitTypeSwitch(instr *typeSwitchInstr) {
+	cases, duplicates := dedupTypeCases(instr.TypeCases)
+	x := fr.value(instr.X)
+	td := fr.getInterfaceTypeDescriptor(x)
+
+	// Group the cases by hash, preserving their order.
+	var hashes []int
+	byhash := make(map[uint32][]ssautil.TypeCase)
+	for _, c := range cases {
+		h := fr.types.getTypeHash(c.Type)
+		if byhash[h] == nil {
+			hashes = append(hashes, int(h))
+		}
+		byhash[h] = append(byhash[h], c)
+	}
+	sort.Ints(hashes)
+
+	nomatchbb := llvm.AddBasicBlock(fr.function, "")
+	hashbb := llvm.AddBasicBlock(fr.function, "")
+	isnull := fr.builder.CreateIsNull(td, "")
+	fr.builder.CreateCondBr(isnull, nomatchbb, hashbb)
+
+	fr.builder.SetInsertPointAtEnd(hashbb)
+	commontd := fr.builder.CreateBitCast(td, llvm.PointerType(fr.types.commonTypeType, 0), "")
+	hash := fr.builder.CreateLoad(fr.builder.CreateStructGEP(commontd, 4, ""), "")
+	hashswitch := fr.builder.CreateSwitch(hash, nomatchbb, len(hashes))
+
+	bindbbs := make(map[*ssa.BasicBlock]llvm.BasicBlock)
+	for _, h := range hashes {
+		hashcasebb := llvm.AddBasicBlock(fr.function, "")
+		hashswitch.AddCase(llvm.ConstInt(llvm.Int32Type(), uint64(h), false), hashcasebb)
+		fr.builder.SetInsertPointAtEnd(hashcasebb)
+		for _, c := range byhash[uint32(h)] {
+			tytd := fr.types.ToRuntime(c.Type)
+			equal := fr.runtime.typeDescriptorsEqual.call(fr, td, tytd)[0]
+			equal = fr.builder.CreateTrunc(equal, llvm.Int1Type(), "")
+			bindbb := llvm.AddBasicBlock(fr.function, "")
+			nextbb := llvm.AddBasicBlock(fr.function, "")
+			fr.builder.CreateCondBr(equal, bindbb, nextbb)
+			bindbbs[c.Block] = bindbb
+			fr.builder.SetInsertPointAtEnd(nextbb)
+		}
+		fr.builder.CreateBr(nomatchbb)
+	}
+
+	fr.builder.SetInsertPointAtEnd(nomatchbb)
+	fr.builder.CreateBr(fr.block(instr.Default))
+	fr.lastBlocks[instr.TypeCases[len(instr.TypeCases)-1].Block.Index] = nomatchbb
+
+	// The bodies of duplicate cases are unreachable, but may still
+	// refer to their bindings.
+	for _, c := range duplicates {
+		fr.env[c.Binding] = newValue(llvm.Undef(fr.llvmtypes.ToLLVM(c.Type)), c.Type)
+	}
+
+	// The block binding the first case must be emitted last, so that
+	// translateBlock records it as the last block of the start block.
+	for i := len(cases) - 1; i >= 0; i-- {
+		c := cases[i]
+		bindbb := bindbbs[c.Block]
+		fr.builder.SetInsertPointAtEnd(bindbb)
+		fr.env[c.Binding] = fr.getInterfaceValue(x, c.Type)
+		fr.builder.CreateBr(fr.block(c.Body))
+		fr.lastBlocks[c.Block.Index] = bindbb
+	}
+}
+
+// dedupTypeCases separates duplicate type cases. As the type assertions
+// are made in order, the first of a set of duplicate cases is kept.
+func dedupTypeCases(in []ssautil.TypeCase) (unique, duplicates []ssautil.TypeCase) {
+	unique = make([]ssautil.TypeCase, 0, len(in))
+dedup:
+	for i, c1 := range in {
+		for _, c2 := range in[:i] {
+			if types.Identical(c1.Type, c2.Type) {
+				duplicates = append(duplicates, c1)
+				continue dedup
+			}
+		}
+		unique = append(unique, c1)
+	}
+	return unique, duplicates
+}
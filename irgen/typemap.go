@@ -62,6 +62,7 @@ type TypeMap struct {
 	types, algs    typeutil.Map
 	runtime        *runtimeInterface
 	methodResolver MethodResolver
+	reachability   *reachability
 	types.MethodSetCache
 
 	commonTypeType, uncommonTypeType, ptrTypeType, funcTypeType, arrayTypeType, sliceTypeType, mapTypeType, chanTypeType, interfaceTypeType, structTypeType llvm.Type
@@ -1556,7 +1557,11 @@ func (tm *TypeMap) getImtPointer(srctype types.Type, targettype *types.Interface
 	for i, targetm := range orderedMethodSet(targetms) {
 		srcm := srcms.Lookup(targetm.Obj().Pkg(), targetm.Obj().Name())
 
-		elems[i+1] = tm.methodResolver.ResolveMethod(srcm).value
+		if tm.reachability.methodLive(srcm) {
+			elems[i+1] = tm.methodResolver.ResolveMethod(srcm).value
+		} else {
+			elems[i+1] = llvm.ConstPointerNull(i8ptr)
+		}
 	}
 	imtinit := llvm.ConstArray(i8ptr, elems)
 
@@ -1940,8 +1945,7 @@ func (tm *TypeMap) makeUncommonTypePtr(t types.Type) llvm.Value {
 
 		sel := omset[i]
 		mname := sel.Obj().Name()
-		mfunc := tm.methodResolver.ResolveMethod(sel)
-		ftyp := mfunc.Type().(*types.Signature)
+		ftyp := sel.Type().(*types.Signature)
 
 		// name
 		mvals[0] = tm.globalStringPtr(mname)
@@ -1967,8 +1971,12 @@ func (tm *TypeMap) makeUncommonTypePtr(t types.Type) llvm.Value {
 		rftyp := types.NewSignature(nil, nil, types.NewTuple(rfparams...), ftyp.Results(), ftyp.Variadic())
 		mvals[3] = tm.getTypeDescriptorPointer(rftyp)
 
-		// function
-		mvals[4] = mfunc.value
+		// function; unreachable methods are left null in whole-program mode
+		if tm.reachability.methodLive(sel) {
+			mvals[4] = tm.methodResolver.ResolveMethod(sel).value
+		} else {
+			mvals[4] = llvm.ConstPointerNull(llvm.PointerType(llvm.Int8Type(), 0))
+		}
 
 		methods[i] = llvm.ConstNamedStruct(tm.methodType, mvals[:])
 	}
@@ -14,6 +14,7 @@
 package irgen
 
 import (
+	"llvm.org/llgo/third_party/gotools/go/ssa"
 	"llvm.org/llgo/third_party/gotools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
@@ -38,3 +39,15 @@ func (fr *frame) loadOrNull(cond, ptr llvm.Value, ty types.Type) *govalue {
 	)
 	return newValue(llv, ty)
 }
+
+// valueReferrers returns the instructions which use v, other than debug
+// references, which describe v without requiring it to be emitted.
+func valueReferrers(v ssa.Value) []ssa.Instruction {
+	var refs []ssa.Instruction
+	for _, ref := range *v.Referrers() {
+		if _, ok := ref.(*ssa.DebugRef); !ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
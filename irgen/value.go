@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"go/token"
 	"llvm.org/llgo/third_party/gotools/go/exact"
+	"llvm.org/llgo/third_party/gotools/go/ssa"
 	"llvm.org/llgo/third_party/gotools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
@@ -43,6 +44,27 @@ func (v *govalue) Type() types.Type {
 	return v.typ
 }
 
+// constString returns a constant of the LLVM string type holding strval.
+func (u *unit) constString(strval string) llvm.Value {
+	strlen := len(strval)
+	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	var ptr llvm.Value
+	if strlen > 0 {
+		init := llvm.ConstString(strval, false)
+		ptr = llvm.AddGlobal(u.module.Module, init.Type(), "")
+		ptr.SetInitializer(init)
+		ptr.SetLinkage(llvm.InternalLinkage)
+		ptr = llvm.ConstBitCast(ptr, i8ptr)
+	} else {
+		ptr = llvm.ConstNull(i8ptr)
+	}
+	len_ := llvm.ConstInt(u.types.inttype, uint64(strlen), false)
+	llvmvalue := llvm.Undef(u.types.stringType)
+	llvmvalue = llvm.ConstInsertValue(llvmvalue, ptr, []uint32{0})
+	llvmvalue = llvm.ConstInsertValue(llvmvalue, len_, []uint32{1})
+	return llvmvalue
+}
+
 // newValueFromConst converts a constant value to an LLVM value.
 func (fr *frame) newValueFromConst(v exact.Value, typ types.Type) *govalue {
 	switch {
@@ -54,25 +76,7 @@ func (fr *frame) newValueFromConst(v exact.Value, typ types.Type) *govalue {
 		if isUntyped(typ) {
 			typ = types.Typ[types.String]
 		}
-		llvmtyp := fr.types.ToLLVM(typ)
-		strval := exact.StringVal(v)
-		strlen := len(strval)
-		i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
-		var ptr llvm.Value
-		if strlen > 0 {
-			init := llvm.ConstString(strval, false)
-			ptr = llvm.AddGlobal(fr.module.Module, init.Type(), "")
-			ptr.SetInitializer(init)
-			ptr.SetLinkage(llvm.InternalLinkage)
-			ptr = llvm.ConstBitCast(ptr, i8ptr)
-		} else {
-			ptr = llvm.ConstNull(i8ptr)
-		}
-		len_ := llvm.ConstInt(fr.types.inttype, uint64(strlen), false)
-		llvmvalue := llvm.Undef(llvmtyp)
-		llvmvalue = llvm.ConstInsertValue(llvmvalue, ptr, []uint32{0})
-		llvmvalue = llvm.ConstInsertValue(llvmvalue, len_, []uint32{1})
-		return newValue(llvmvalue, typ)
+		return newValue(fr.constString(exact.StringVal(v)), typ)
 
 	case isInteger(typ):
 		if isUntyped(typ) {
@@ -219,37 +223,37 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		switch op {
 		case token.QUO:
 			// (a+bi)/(c+di) = (ac+bd)/(c**2+d**2) + (bc-ad)/(c**2+d**2)i
-			ac := b.CreateFMul(a_, c_, "")
-			bd := b.CreateFMul(b_, d_, "")
-			bc := b.CreateFMul(b_, c_, "")
-			ad := b.CreateFMul(a_, d_, "")
-			cpow2 := b.CreateFMul(c_, c_, "")
-			dpow2 := b.CreateFMul(d_, d_, "")
-			denom := b.CreateFAdd(cpow2, dpow2, "")
-			realnumer := b.CreateFAdd(ac, bd, "")
-			imagnumer := b.CreateFSub(bc, ad, "")
-			real_ := b.CreateFDiv(realnumer, denom, "")
-			imag_ := b.CreateFDiv(imagnumer, denom, "")
+			ac := fr.createFBinOp(llvm.FMul, a_, c_)
+			bd := fr.createFBinOp(llvm.FMul, b_, d_)
+			bc := fr.createFBinOp(llvm.FMul, b_, c_)
+			ad := fr.createFBinOp(llvm.FMul, a_, d_)
+			cpow2 := fr.createFBinOp(llvm.FMul, c_, c_)
+			dpow2 := fr.createFBinOp(llvm.FMul, d_, d_)
+			denom := fr.createFBinOp(llvm.FAdd, cpow2, dpow2)
+			realnumer := fr.createFBinOp(llvm.FAdd, ac, bd)
+			imagnumer := fr.createFBinOp(llvm.FSub, bc, ad)
+			real_ := fr.createFBinOp(llvm.FDiv, realnumer, denom)
+			imag_ := fr.createFBinOp(llvm.FDiv, imagnumer, denom)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.MUL:
 			// (a+bi)(c+di) = (ac-bd)+(bc+ad)i
-			ac := b.CreateFMul(a_, c_, "")
-			bd := b.CreateFMul(b_, d_, "")
-			bc := b.CreateFMul(b_, c_, "")
-			ad := b.CreateFMul(a_, d_, "")
-			real_ := b.CreateFSub(ac, bd, "")
-			imag_ := b.CreateFAdd(bc, ad, "")
+			ac := fr.createFBinOp(llvm.FMul, a_, c_)
+			bd := fr.createFBinOp(llvm.FMul, b_, d_)
+			bc := fr.createFBinOp(llvm.FMul, b_, c_)
+			ad := fr.createFBinOp(llvm.FMul, a_, d_)
+			real_ := fr.createFBinOp(llvm.FSub, ac, bd)
+			imag_ := fr.createFBinOp(llvm.FAdd, bc, ad)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.ADD:
-			real_ := b.CreateFAdd(a_, c_, "")
-			imag_ := b.CreateFAdd(b_, d_, "")
+			real_ := fr.createFBinOp(llvm.FAdd, a_, c_)
+			imag_ := fr.createFBinOp(llvm.FAdd, b_, d_)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.SUB:
-			real_ := b.CreateFSub(a_, c_, "")
-			imag_ := b.CreateFSub(b_, d_, "")
+			real_ := fr.createFBinOp(llvm.FSub, a_, c_)
+			imag_ := fr.createFBinOp(llvm.FSub, b_, d_)
 			lhsval = b.CreateInsertValue(lhsval, real_, 0, "")
 			result = b.CreateInsertValue(lhsval, imag_, 1, "")
 		case token.EQL:
@@ -270,7 +274,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 	switch op {
 	case token.MUL:
 		if isFloat(lhs.typ) {
-			result = b.CreateFMul(lhs.value, rhs.value, "")
+			result = fr.createFBinOp(llvm.FMul, lhs.value, rhs.value)
 		} else {
 			result = b.CreateMul(lhs.value, rhs.value, "")
 		}
@@ -278,7 +282,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 	case token.QUO:
 		switch {
 		case isFloat(lhs.typ):
-			result = b.CreateFDiv(lhs.value, rhs.value, "")
+			result = fr.createFBinOp(llvm.FDiv, lhs.value, rhs.value)
 		case !isUnsigned(lhs.typ):
 			result = b.CreateSDiv(lhs.value, rhs.value, "")
 		default:
@@ -297,14 +301,14 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		return newValue(result, lhs.typ)
 	case token.ADD:
 		if isFloat(lhs.typ) {
-			result = b.CreateFAdd(lhs.value, rhs.value, "")
+			result = fr.createFBinOp(llvm.FAdd, lhs.value, rhs.value)
 		} else {
 			result = b.CreateAdd(lhs.value, rhs.value, "")
 		}
 		return newValue(result, lhs.typ)
 	case token.SUB:
 		if isFloat(lhs.typ) {
-			result = b.CreateFSub(lhs.value, rhs.value, "")
+			result = fr.createFBinOp(llvm.FSub, lhs.value, rhs.value)
 		} else {
 			result = b.CreateSub(lhs.value, rhs.value, "")
 		}
@@ -383,6 +387,65 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 	panic("unreachable")
 }
 
+// contractibleMul returns the floating-point multiplication operand of the
+// addition or subtraction v that may be contracted into it, or nil. The
+// first operand is preferred.
+func contractibleMul(v *ssa.BinOp) *ssa.BinOp {
+	if v.Op != token.ADD && v.Op != token.SUB || !isFloat(v.Type()) || v.X == v.Y {
+		return nil
+	}
+	for _, x := range [...]ssa.Value{v.X, v.Y} {
+		if x, ok := x.(*ssa.BinOp); ok && x.Op == token.MUL {
+			return x
+		}
+	}
+	return nil
+}
+
+// isContractedMul reports whether v is a floating-point multiplication
+// that is contracted into each of its uses, according to the
+// floating-point contraction mode. Such a multiplication is not emitted
+// itself.
+func (fr *frame) isContractedMul(v *ssa.BinOp) bool {
+	if v.Op != token.MUL || !isFloat(v.Type()) {
+		return false
+	}
+	refs := valueReferrers(v)
+	if len(refs) == 0 || len(refs) > 1 && fr.FPContract != FPContractFast {
+		return false
+	}
+	for _, ref := range refs {
+		if user, ok := ref.(*ssa.BinOp); !ok || contractibleMul(user) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mulAdd emits the addition or subtraction v, with the multiplication mul
+// contracted into it, as a call to llvm.fmuladd.
+func (fr *frame) mulAdd(v, mul *ssa.BinOp) *govalue {
+	a := fr.value(mul.X)
+	b := fr.value(mul.Y)
+	var c *govalue
+	if v.X == mul {
+		// a*b + c, a*b - c
+		c = fr.value(v.Y)
+		if v.Op == token.SUB {
+			c = fr.unaryOp(c, token.SUB)
+		}
+	} else {
+		// c + a*b, c - a*b
+		c = fr.value(v.X)
+		if v.Op == token.SUB {
+			a = fr.unaryOp(a, token.SUB)
+		}
+	}
+	fmuladd := fr.getIntrinsic(intrinsicFMulAdd, a.value.Type())
+	result := fr.builder.CreateCall(fmuladd, []llvm.Value{a.value, b.value, c.value}, "")
+	return newValue(result, v.Type())
+}
+
 func (fr *frame) shift(lhs *govalue, rhs *govalue, op token.Token) *govalue {
 	rhs = fr.convert(rhs, lhs.Type())
 	lhsval := lhs.value
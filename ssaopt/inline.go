@@ -0,0 +1,80 @@
+// Copyright 2015 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package ssaopt
+
+import (
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+)
+
+// DefaultInlineThreshold is the default maximum cost, as computed by
+// InlineCost, of a function whose body is inlined at its call sites.
+const DefaultInlineThreshold = 30
+
+// InlineCost returns an estimate of the size of the code generated for f.
+// Instructions that generate no code are free, and instructions that
+// involve a call into the runtime or another function are charged more
+// than simple arithmetic and memory operations.
+func InlineCost(f *ssa.Function) int {
+	cost := 0
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.DebugRef, *ssa.Jump, *ssa.Phi:
+				continue
+
+			case *ssa.Call:
+				if _, ok := instr.Call.Value.(*ssa.Builtin); ok {
+					cost += 2
+				} else {
+					cost += 5
+				}
+
+			case *ssa.Go, *ssa.MakeChan, *ssa.MakeClosure, *ssa.MakeMap, *ssa.MapUpdate, *ssa.Lookup, *ssa.Range, *ssa.Next, *ssa.Select, *ssa.Send, *ssa.Panic:
+				cost += 5
+
+			case *ssa.Alloc:
+				if instr.Heap {
+					cost += 5
+				} else {
+					cost++
+				}
+
+			default:
+				cost++
+			}
+		}
+	}
+	return cost
+}
+
+// Inline inlines into f those static calls whose callees have a cost,
+// as computed by InlineCost, of at most threshold. Calls introduced by
+// inlining are not considered in turn, which bounds the growth of f.
+func Inline(f *ssa.Function, threshold int) {
+	var calls []*ssa.Call
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				calls = append(calls, call)
+			}
+		}
+	}
+
+	costs := make(map[*ssa.Function]int)
+	for _, call := range calls {
+		callee := call.Call.StaticCallee()
+		if callee == nil || !ssa.CanInline(callee) {
+			continue
+		}
+		cost, ok := costs[callee]
+		if !ok {
+			cost = InlineCost(callee)
+			costs[callee] = cost
+		}
+		if cost <= threshold {
+			ssa.InlineCall(call)
+		}
+	}
+}
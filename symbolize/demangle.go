@@ -0,0 +1,293 @@
+//===- demangle.go - demangling of llgo symbol names -----------------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This file implements the demangling of the function names produced by
+// irgen's mangleFunctionName, which follows the scheme used by gccgo.
+//
+//===----------------------------------------------------------------------===//
+
+package symbolize
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SymbolKind distinguishes ordinary functions and methods from the
+// wrappers synthesized for method values and method expressions.
+type SymbolKind int
+
+const (
+	// Func is an ordinary function, method or function literal.
+	Func SymbolKind = iota
+	// MethodValue is the wrapper binding a receiver to a method.
+	MethodValue
+	// MethodExpr is the wrapper taking a method's receiver as its
+	// first parameter.
+	MethodExpr
+)
+
+// A Symbol is the Go form of a mangled function name.
+type Symbol struct {
+	// Package is the path of the package declaring the function, in its
+	// mangled form, in which '/' and '.' are replaced by '_'. The names
+	// of ordinary functions and methods only record the mangled form, so
+	// the paths recorded in full by other names are mangled too, and
+	// each package is named alike wherever it appears.
+	Package string
+
+	// Receiver is the receiver type of a method, such as "T" or "*T",
+	// or empty if the function is not a method.
+	Receiver string
+
+	// Name is the name of the function or method. Declared init
+	// functions keep the numbered names they are mangled with, such as
+	// "init#1", as they cannot be referred to in Go.
+	Name string
+
+	// Closure holds the indices of the function literals enclosing the
+	// function, outermost first, counting from 1. It is empty if the
+	// symbol does not name a function literal.
+	Closure []int
+
+	Kind SymbolKind
+}
+
+// String returns the name of the function in the form used by the gc
+// toolchain, such as "pkg.F", "pkg.(*T).M", "pkg.F.func1.2" or
+// "pkg.T.M-fm".
+func (s *Symbol) String() string {
+	var b []byte
+	if s.Package != "" {
+		b = append(b, s.Package...)
+		b = append(b, '.')
+	}
+	switch {
+	case strings.HasPrefix(s.Receiver, "*"):
+		b = append(b, '(')
+		b = append(b, s.Receiver...)
+		b = append(b, ")."...)
+	case s.Receiver != "":
+		b = append(b, s.Receiver...)
+		b = append(b, '.')
+	}
+	b = append(b, s.Name...)
+	if s.Kind == MethodValue {
+		b = append(b, "-fm"...)
+	}
+	for i, index := range s.Closure {
+		b = append(b, '.')
+		if i == 0 {
+			b = append(b, "func"...)
+		}
+		b = strconv.AppendInt(b, int64(index), 10)
+	}
+	return string(b)
+}
+
+var errNotGo = errors.New("not a Go function name")
+
+// Parse parses the mangled function name name.
+func Parse(name string) (*Symbol, error) {
+	// Function literals are mangled as the name of the enclosing
+	// function, a colon and the SSA name of the literal, which
+	// records the whole chain of enclosing functions.
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		s, err := parseSSAName(name[i+1:])
+		if err != nil || len(s.Closure) == 0 {
+			return nil, errNotGo
+		}
+		return s, nil
+	}
+	// Synthetic wrappers are mangled as their SSA names.
+	if strings.HasSuffix(name, "$bound") || strings.HasSuffix(name, "$thunk") {
+		return parseSSAName(name)
+	}
+	return parseMangled(name)
+}
+
+// Demangle returns the Go form of the mangled function name name, as
+// described by Symbol.String, or name itself if it cannot be parsed.
+func Demangle(name string) string {
+	s, err := Parse(name)
+	if err != nil {
+		return name
+	}
+	return s.String()
+}
+
+// parseSSAName parses a function name produced by ssa.Function.String,
+// such as "pkg/path.F$1" or "(*pkg/path.T).M$bound".
+func parseSSAName(name string) (*Symbol, error) {
+	parts := strings.Split(name, "$")
+	var s Symbol
+	for _, part := range parts[1:] {
+		switch part {
+		case "bound":
+			s.Kind = MethodValue
+		case "thunk":
+			s.Kind = MethodExpr
+		default:
+			index, err := strconv.Atoi(part)
+			if err != nil || index <= 0 {
+				return nil, errNotGo
+			}
+			s.Closure = append(s.Closure, index)
+		}
+	}
+
+	base := parts[0]
+	if strings.HasPrefix(base, "(") {
+		// Methods are named as the parenthesized, package-qualified
+		// receiver type and the method name.
+		end := strings.Index(base, ").")
+		if end < 0 {
+			return nil, errNotGo
+		}
+		recv := base[1:end]
+		base = base[end+2:]
+		ptr := strings.HasPrefix(recv, "*")
+		recv = strings.TrimPrefix(recv, "*")
+		if dot := strings.LastIndex(recv, "."); dot >= 0 {
+			s.Package, recv = recv[:dot], recv[dot+1:]
+		}
+		if !isIdent(recv) {
+			return nil, errNotGo
+		}
+		if ptr {
+			recv = "*" + recv
+		}
+		s.Receiver = recv
+	} else if dot := strings.LastIndex(base, "."); dot >= 0 {
+		s.Package, base = base[:dot], base[dot+1:]
+	}
+	if !isIdent(base) || (s.Package == "" && s.Receiver == "") {
+		return nil, errNotGo
+	}
+	s.Package = manglePath(s.Package)
+	s.Name = base
+	return &s, nil
+}
+
+// parseMangled parses the name of an ordinary function or method, which
+// is mangled as the mangled package path, a dot and the function name,
+// followed for methods by a dot and the mangled receiver type. Methods
+// promoted from embedded fields may lack the package path.
+func parseMangled(name string) (*Symbol, error) {
+	dot := strings.Index(name, ".")
+	if dot < 0 {
+		return nil, errNotGo
+	}
+	first, rest := name[:dot], name[dot+1:]
+	if pkg, recv, err := parseReceiver(rest); err == nil && pkg != "" && isIdent(first) {
+		return &Symbol{Package: pkg, Receiver: recv, Name: first}, nil
+	}
+	if !isMangledPath(first) {
+		return nil, errNotGo
+	}
+
+	s := &Symbol{Package: first}
+	if rest == ".import" {
+		// The package initializer.
+		s.Name = "init"
+		return s, nil
+	}
+	if dot := strings.Index(rest, "."); dot >= 0 {
+		pkg, recv, err := parseReceiver(rest[dot+1:])
+		if err != nil || pkg != s.Package {
+			return nil, errNotGo
+		}
+		s.Receiver = recv
+		rest = rest[:dot]
+	}
+	// Declared init functions are numbered, as in "init#1", and are
+	// left so.
+	fn := rest
+	if hash := strings.Index(rest, "#"); hash >= 0 {
+		if _, err := strconv.Atoi(rest[hash+1:]); err != nil {
+			return nil, errNotGo
+		}
+		fn = rest[:hash]
+	}
+	if !isIdent(fn) {
+		return nil, errNotGo
+	}
+	s.Name = rest
+	return s, nil
+}
+
+// parseReceiver parses the mangled receiver type of a method, which is a
+// named type or a pointer to one, and returns its package and its Go form.
+func parseReceiver(t string) (pkg, recv string, err error) {
+	ptr := strings.HasPrefix(t, "p")
+	if ptr {
+		t = t[1:]
+	}
+	if !strings.HasPrefix(t, "N") {
+		return "", "", errNotGo
+	}
+	sep := strings.Index(t, "_")
+	if sep < 0 {
+		return "", "", errNotGo
+	}
+	n, err := strconv.Atoi(t[1:sep])
+	if err != nil || n != len(t)-sep-1 {
+		return "", "", errNotGo
+	}
+	named := t[sep+1:]
+	if dot := strings.LastIndex(named, "."); dot >= 0 {
+		pkg, named = named[:dot], named[dot+1:]
+	}
+	// Types declared within functions are mangled with the name of the
+	// function and a scope number, separated by '$'.
+	if !isIdent(strings.Replace(named, "$", "", -1)) {
+		return "", "", errNotGo
+	}
+	if ptr {
+		named = "*" + named
+	}
+	return pkg, named, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// manglePath returns the mangled form of the package path path, as used
+// by irgen.ManglePackagePath.
+func manglePath(path string) string {
+	path = strings.Replace(path, "/", "_", -1)
+	return strings.Replace(path, ".", "_", -1)
+}
+
+func isMangledPath(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c == '_', c == '-', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
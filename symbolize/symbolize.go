@@ -0,0 +1,124 @@
+//===- symbolize.go - symbolization of llgo program output -----------------===//
+//
+//                     The LLVM Compiler Infrastructure
+//
+// This file is distributed under the University of Illinois Open Source
+// License. See LICENSE.TXT for details.
+//
+//===----------------------------------------------------------------------===//
+//
+// This package translates the mangled function names of llgo programs into
+// their Go forms, in crash output, perf script output and the like.
+//
+//===----------------------------------------------------------------------===//
+
+package symbolize
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// isSymbolChar reports whether c may appear in a mangled function name.
+func isSymbolChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("_./$:#*()-", c) >= 0
+}
+
+// symbolPrefix returns the length of the longest prefix of word that is a
+// mangled function name, and its Go form, or 0 if there is none. word may
+// run on into an argument list, as in "main.F(0x1)", so prefixes ending
+// before each opening parenthesis are tried in turn.
+func symbolPrefix(word string) (int, string) {
+	for n := len(word); n > 0; {
+		if s, err := Parse(word[:n]); err == nil {
+			return n, s.String()
+		}
+		n = strings.LastIndex(word[:n], "(")
+	}
+	return 0, ""
+}
+
+// Line returns line with each mangled function name replaced by its Go
+// form.
+func Line(line string) string {
+	var b []byte
+	start := 0
+	for i := 0; i < len(line); {
+		if !isSymbolChar(line[i]) || (i > 0 && isSymbolChar(line[i-1])) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(line) && isSymbolChar(line[end]) {
+			end++
+		}
+		n, sym := symbolPrefix(line[i:end])
+		if n == 0 || line[i:i+n] == sym {
+			i = end
+			continue
+		}
+		b = append(b, line[start:i]...)
+		b = append(b, sym...)
+		i += n
+		start = i
+	}
+	if b == nil {
+		return line
+	}
+	return string(append(b, line[start:]...))
+}
+
+// goroutineHeader returns the ID of the goroutine whose stack follows the
+// line, as printed by the runtime in a traceback, or -1 if the line is not
+// a goroutine header.
+func goroutineHeader(line string) int {
+	if !strings.HasPrefix(line, "goroutine ") {
+		return -1
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "[") {
+		return -1
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// Symbolize copies the output of an llgo program, or of a tool such as
+// perf inspecting one, from r to w, replacing the mangled function names
+// in each line with their Go forms. If goroutine is not negative, the
+// stacks of goroutines other than the one with that ID are omitted from
+// tracebacks; each stack starts at its goroutine header and ends at the
+// following empty line.
+func Symbolize(w io.Writer, r io.Reader, goroutine int) error {
+	scanner := bufio.NewScanner(r)
+	bw := bufio.NewWriter(w)
+	current := -1
+	for scanner.Scan() {
+		line := scanner.Text()
+		if id := goroutineHeader(line); id >= 0 {
+			current = id
+		}
+		skip := goroutine >= 0 && current >= 0 && current != goroutine
+		if line == "" {
+			current = -1
+		}
+		if skip {
+			continue
+		}
+		bw.WriteString(Line(line))
+		bw.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
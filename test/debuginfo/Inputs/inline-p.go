@@ -0,0 +1,10 @@
+package p
+
+type Buffer struct {
+	buf []byte
+	off int
+}
+
+func (b *Buffer) Len() int {
+	return len(b.buf) - b.off
+}
@@ -0,0 +1,49 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+import "unsafe"
+
+var (
+	vbool       bool
+	vint        int
+	vint8       int8
+	vint16      int16
+	vint32      int32
+	vint64      int64
+	vuint       uint
+	vuint8      uint8
+	vuint16     uint16
+	vuint32     uint32
+	vuint64     uint64
+	vuintptr    uintptr
+	vfloat32    float32
+	vfloat64    float64
+	vcomplex64  complex64
+	vcomplex128 complex128
+	vstring     string
+	vpointer    unsafe.Pointer
+	vbyte       byte
+	vrune       rune
+)
+
+// CHECK-DAG: !DIBasicType(name: "bool", size: 8, align: 8, encoding: DW_ATE_boolean)
+// CHECK-DAG: !DIBasicType(name: "int", size: 64, align: 64, encoding: DW_ATE_signed)
+// CHECK-DAG: !DIBasicType(name: "int8", size: 8, align: 8, encoding: DW_ATE_signed)
+// CHECK-DAG: !DIBasicType(name: "int16", size: 16, align: 16, encoding: DW_ATE_signed)
+// CHECK-DAG: !DIBasicType(name: "int32", size: 32, align: 32, encoding: DW_ATE_signed)
+// CHECK-DAG: !DIBasicType(name: "int64", size: 64, align: 64, encoding: DW_ATE_signed)
+// CHECK-DAG: !DIBasicType(name: "uint", size: 64, align: 64, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "uint8", size: 8, align: 8, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "uint16", size: 16, align: 16, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "uint32", size: 32, align: 32, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "uint64", size: 64, align: 64, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "uintptr", size: 64, align: 64, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "float32", size: 32, align: 32, encoding: DW_ATE_float)
+// CHECK-DAG: !DIBasicType(name: "float64", size: 64, align: 64, encoding: DW_ATE_float)
+// CHECK-DAG: !DIBasicType(name: "complex64", size: 64, align: 32, encoding: DW_ATE_complex_float)
+// CHECK-DAG: !DIBasicType(name: "complex128", size: 128, align: 64, encoding: DW_ATE_complex_float)
+// CHECK-DAG: !DICompositeType(tag: DW_TAG_structure_type, name: "string", size: 128, align: 64
+// CHECK-DAG: !DIBasicType(name: "unsafe.Pointer", size: 64, align: 64, encoding: DW_ATE_unsigned)
+// CHECK-DAG: !DIBasicType(name: "byte", size: 8, align: 8, encoding: DW_ATE_unsigned_char)
+// CHECK-DAG: !DIBasicType(name: "rune", size: 32, align: 32, encoding: DW_ATE_UTF)
@@ -0,0 +1,53 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+// RUN: llgo -fgo-pkgpath=example.com/foo -S -emit-llvm -g -o - %s | FileCheck --check-prefix=PKGPATH %s
+
+package foo
+
+type T struct{ n int }
+
+func (t *T) M() int { return t.n }
+
+func Outer(a int) func() int {
+	x := a * 2
+	f := func() int {
+		return x + a
+	}
+	return f
+}
+
+func Nested() func() func() int {
+	y := 1
+	return func() func() int {
+		return func() int {
+			return y
+		}
+	}
+}
+
+func MethodValue(t *T) func() int {
+	return t.M
+}
+
+// The captured variables are described by dereferencing their addresses
+// in the closure context, which follow the function pointer.
+// CHECK-DAG: call void @llvm.dbg.value(metadata i8* %{{.*}}, i64 0, metadata ![[X:[0-9]+]], metadata ![[XEXPR:[0-9]+]])
+// CHECK-DAG: call void @llvm.dbg.value(metadata i8* %{{.*}}, i64 0, metadata ![[A:[0-9]+]], metadata ![[AEXPR:[0-9]+]])
+
+// CHECK-DAG: ![[FUNC1:[0-9]+]] = distinct !DISubprogram(name: "foo.Outer.func1", linkageName: "{{.*}}Outer{{.*}}"
+// CHECK-DAG: ![[X]] = !DILocalVariable(name: "x", scope: ![[FUNC1]], {{.*}}line: 10
+// CHECK-DAG: ![[A]] = !DILocalVariable(name: "a", scope: ![[FUNC1]], {{.*}}line: 9
+// CHECK-DAG: ![[XEXPR]] = !DIExpression(DW_OP_plus, 8, DW_OP_deref, DW_OP_deref)
+// CHECK-DAG: ![[AEXPR]] = !DIExpression(DW_OP_plus, 16, DW_OP_deref, DW_OP_deref)
+
+// CHECK-DAG: !DISubprogram(name: "foo.Nested.func1",
+// CHECK-DAG: ![[FUNC11:[0-9]+]] = distinct !DISubprogram(name: "foo.Nested.func1.1",
+// CHECK-DAG: !DILocalVariable(name: "y", scope: ![[FUNC11]], {{.*}}line: 18
+
+// The receiver of a method value is captured by value.
+// CHECK-DAG: ![[FM:[0-9]+]] = distinct !DISubprogram(name: "foo.(*T).M-fm",
+// CHECK-DAG: !DILocalVariable(name: "recv", scope: ![[FM]],
+// CHECK-DAG: !DIExpression(DW_OP_plus, 8, DW_OP_deref)
+
+// Names are qualified by the mangled package path, as symbols are.
+// PKGPATH-DAG: !DISubprogram(name: "example_com_foo.Outer.func1",
+// PKGPATH-DAG: !DISubprogram(name: "example_com_foo.(*T).M-fm",
@@ -0,0 +1,9 @@
+// RUN: llgo -S -emit-llvm -g -fgdb-scripts -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck --check-prefix=NOSCRIPTS %s
+
+package foo
+
+// CHECK: $__llgo_gdb_script = comdat any
+// CHECK: @__llgo_gdb_script = weak_odr constant [{{[0-9]+}} x i8] c"\01{{.*}}/share/llgo/llgo-gdb.py\00", section ".debug_gdb_scripts", comdat, align 1
+
+// NOSCRIPTS-NOT: .debug_gdb_scripts
@@ -0,0 +1,22 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+var Exported int
+
+var unexported struct {
+	a, b int32
+}
+
+var p = &[64]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17}
+
+var q = &[64]int{17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+// CHECK-DAG: !DIGlobalVariable(name: "foo.Exported", linkageName: "foo.Exported", {{.*}}line: 5, {{.*}}isLocal: false, isDefinition: true
+// CHECK-DAG: !DIGlobalVariable(name: "foo.unexported", linkageName: "foo.unexported", {{.*}}line: 7, {{.*}}isLocal: true, isDefinition: true
+// CHECK-DAG: !DIGlobalVariable(name: "foo.p", {{.*}}line: 11,
+// CHECK-DAG: !DIGlobalVariable(name: "foo.q", {{.*}}line: 13,
+
+// Statically allocated composite literals are numbered.
+// CHECK-DAG: !DIGlobalVariable(name: "foo.init.complit.1", {{.*}}line: 11, {{.*}}isLocal: true
+// CHECK-DAG: !DIGlobalVariable(name: "foo.init.complit.2", {{.*}}line: 13, {{.*}}isLocal: true
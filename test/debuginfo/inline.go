@@ -0,0 +1,27 @@
+// RUN: llgo -g -fgo-pkgpath=p -finline-limit=30 -c -o %T/p.o %S/Inputs/inline-p.go
+// RUN: llgo -g -O2 -fgo-pkgpath=q -finline-limit=30 -I %T -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -O2 -fgo-pkgpath=q -finline-limit=30 -I %T -S -emit-llvm -o - %s | FileCheck --check-prefix=NODEBUG %s
+
+package q
+
+import "p"
+
+// The instructions inlined from p.Buffer.Len are located in its scope,
+// inlined at the call site in F.
+
+// CHECK-LABEL: define i64 @q.F
+// CHECK-NOT: call {{.*}} @p.Buffer.Len
+// CHECK: sub i64 {{.*}}, !dbg ![[INLINED:[0-9]+]]
+func F(b *p.Buffer) int {
+	return b.Len()
+}
+
+// CHECK-DAG: ![[INLINED]] = !DILocation(line: 9, {{.*}}inlinedAt: ![[CALL:[0-9]+]])
+// CHECK-DAG: ![[CALL]] = !DILocation(line: 16,
+// CHECK-DAG: !DISubprogram(name: "Len"
+
+// Without -g, the inlined bodies are stripped of their debug info.
+
+// NODEBUG-NOT: !dbg
+// NODEBUG-NOT: llvm.dbg.cu
+// NODEBUG-NOT: DISubprogram
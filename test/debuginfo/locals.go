@@ -0,0 +1,28 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+func F(a int, b string) int {
+	x := a * 2
+	if a > 0 {
+		y := x + 1
+		return y
+	}
+	var z [4]int
+	z[a&3] = x
+	return len(b) + z[0]
+}
+
+// CHECK-DAG: call void @llvm.dbg.value({{.*}}, metadata ![[A:[0-9]+]]
+// CHECK-DAG: call void @llvm.dbg.value({{.*}}, metadata ![[X:[0-9]+]]
+// CHECK-DAG: call void @llvm.dbg.value({{.*}}, metadata ![[Y:[0-9]+]]
+// CHECK-DAG: call void @llvm.dbg.declare({{.*}}, metadata ![[Z:[0-9]+]]
+
+// CHECK-DAG: ![[F:[0-9]+]] = distinct !DISubprogram(name: "{{.*}}F"
+// CHECK-DAG: ![[A]] = !DILocalVariable(name: "a", arg: 1, scope: ![[F]], {{.*}}line: 5
+// CHECK-DAG: !DILocalVariable(name: "b", arg: 2, scope: ![[F]], {{.*}}line: 5
+// CHECK-DAG: ![[X]] = !DILocalVariable(name: "x", scope: ![[F]], {{.*}}line: 6
+// CHECK-DAG: ![[Y]] = !DILocalVariable(name: "y", scope: ![[BODY:[0-9]+]], {{.*}}line: 8
+// CHECK-DAG: ![[BODY]] = distinct !DILexicalBlock(scope: ![[IF:[0-9]+]], {{.*}}line: 7, column: 11)
+// CHECK-DAG: ![[IF]] = distinct !DILexicalBlock(scope: ![[F]], {{.*}}line: 7, column: 2)
+// CHECK-DAG: ![[Z]] = !DILocalVariable(name: "z", scope: ![[F]], {{.*}}line: 11
@@ -0,0 +1,29 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+var M map[string]int
+var C chan int
+var E interface{}
+var I interface {
+	F()
+}
+
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_pointer_type, name: "map[string]int", baseType: ![[MAP:[0-9]+]]
+// CHECK-DAG: ![[MAP]] = !DIDerivedType(tag: DW_TAG_typedef, name: "__go_map", baseType: ![[MAPSTRUCT:[0-9]+]]
+// CHECK-DAG: ![[MAPSTRUCT]] = !DICompositeType(tag: DW_TAG_structure_type, {{.*}}elements: ![[MAPFIELDS:[0-9]+]]
+// CHECK-DAG: ![[MAPFIELDS]] = !{![[DESC:[0-9]+]], ![[COUNT:[0-9]+]], ![[BUCKETCOUNT:[0-9]+]], ![[BUCKETS:[0-9]+]]}
+// CHECK-DAG: ![[BUCKETS]] = !DIDerivedType(tag: DW_TAG_member, name: "__buckets"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_typedef, name: "__go_map_entry"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "__key"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "__val"
+
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_pointer_type, name: "chan int", baseType: ![[CHAN:[0-9]+]]
+// CHECK-DAG: ![[CHAN]] = !DIDerivedType(tag: DW_TAG_typedef, name: "__go_channel"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "elemtype"
+
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_typedef, name: "__go_type_descriptor"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "__type_descriptor"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "__object"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "__methods"
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "F"
@@ -0,0 +1,36 @@
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+func F(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		x := i * 2
+		s += x
+	}
+	{
+		x := n
+		s += x
+	}
+	switch n {
+	case 1:
+		x := 3
+		s += x
+	}
+	return s
+}
+
+// CHECK-DAG: ![[F:[0-9]+]] = distinct !DISubprogram(name: "{{.*}}F"
+// CHECK-DAG: !DILocalVariable(name: "s", scope: ![[F]], {{.*}}line: 6
+
+// CHECK-DAG: ![[FOR:[0-9]+]] = distinct !DILexicalBlock(scope: ![[F]], {{.*}}line: 7, column: 2)
+// CHECK-DAG: !DILocalVariable(name: "i", scope: ![[FOR]], {{.*}}line: 7
+// CHECK-DAG: ![[FORBODY:[0-9]+]] = distinct !DILexicalBlock(scope: ![[FOR]], {{.*}}line: 7, column: 25)
+// CHECK-DAG: !DILocalVariable(name: "x", scope: ![[FORBODY]], {{.*}}line: 8
+
+// CHECK-DAG: ![[BLOCK:[0-9]+]] = distinct !DILexicalBlock(scope: ![[F]], {{.*}}line: 11, column: 2)
+// CHECK-DAG: !DILocalVariable(name: "x", scope: ![[BLOCK]], {{.*}}line: 12
+
+// CHECK-DAG: ![[SWITCH:[0-9]+]] = distinct !DILexicalBlock(scope: ![[F]], {{.*}}line: 15, column: 2)
+// CHECK-DAG: ![[CASE:[0-9]+]] = distinct !DILexicalBlock(scope: ![[SWITCH]], {{.*}}line: 16, column: 2)
+// CHECK-DAG: !DILocalVariable(name: "x", scope: ![[CASE]], {{.*}}line: 17
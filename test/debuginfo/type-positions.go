@@ -0,0 +1,23 @@
+// RUN: llgo -S -emit-llvm -g -fdebug-prefix-map=%S=/src -o - %s | FileCheck %s
+
+package foo
+
+type T struct {
+	A int
+
+	B string
+}
+
+type I interface {
+	M()
+}
+
+func F(t T, i I) {}
+
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_typedef, name: "T", file: ![[FILE:[0-9]+]], line: 5, baseType: ![[TSTRUCT:[0-9]+]])
+// CHECK-DAG: ![[TSTRUCT]] = !DICompositeType(tag: DW_TAG_structure_type, name: "T", file: ![[FILE]], line: 5,
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "A", file: ![[FILE]], line: 6,
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "B", file: ![[FILE]], line: 8,
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_typedef, name: "I", file: ![[FILE]], line: 11,
+// CHECK-DAG: !DIDerivedType(tag: DW_TAG_member, name: "M", file: ![[FILE]], line: 12,
+// CHECK-DAG: ![[FILE]] = !DIFile(filename: "/src/type-positions.go"
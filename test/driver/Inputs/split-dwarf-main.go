@@ -0,0 +1,9 @@
+package main
+
+func F() int {
+	return 1
+}
+
+func main() {
+	println(F())
+}
@@ -0,0 +1,26 @@
+// RUN: llgo -flto -O2 -c -o %t.o %s
+// RUN: llgo -flto -O2 -o %t %t.o
+// RUN: %t 2>&1 | FileCheck %s
+// RUN: llgo -flto -o %t2 %s
+// RUN: %t2 2>&1 | FileCheck %s
+// RUN: llvm-readobj -sections %t.o | FileCheck --check-prefix=SECTIONS %s
+
+// SECTIONS-DAG: Name: .llvmbc
+// SECTIONS-DAG: Name: .llgo_lto_symbols
+
+// CHECK: 42
+// CHECK-NEXT: hello, lto
+
+package main
+
+type T struct{ n int }
+
+func (t *T) double() int { return t.n * 2 }
+
+func greet(name string) string { return "hello, " + name }
+
+func main() {
+	t := &T{21}
+	println(t.double())
+	println(greet("lto"))
+}
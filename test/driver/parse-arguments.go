@@ -6,6 +6,8 @@
 // RUN: not llgo -fload-plugin 2>&1 | FileCheck --check-prefix=fload-plugin %s
 // RUN: not llgo -mllvm 2>&1 | FileCheck --check-prefix=mllvm %s
 // RUN: not llgo -o 2>&1 | FileCheck --check-prefix=o %s
+// RUN: not llgo -c -flto -fno-split-stack %s 2>&1 | FileCheck --check-prefix=nosplit-lto %s
+// RUN: not llgo -c -fsanitize=address -fno-split-stack %s 2>&1 | FileCheck --check-prefix=nosplit-san %s
 
 // B: missing argument after '-B'
 // D: missing argument after '-D'
@@ -15,3 +17,5 @@
 // fload-plugin: missing argument after '-fload-plugin'
 // mllvm: missing argument after '-mllvm'
 // o: missing argument after '-o'
+// nosplit-lto: '-fno-split-stack' is not supported with '-flto'
+// nosplit-san: '-fno-split-stack' is not supported with '-fsanitize'
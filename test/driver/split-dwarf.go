@@ -0,0 +1,38 @@
+// REQUIRES: objcopy
+// RUN: llgo -c -gsplit-dwarf -o %t.o %s
+// RUN: llvm-dwarfdump -debug-dump=info %t.o | FileCheck --check-prefix=SKELETON %s
+// RUN: llvm-dwarfdump -debug-dump=info.dwo %t.dwo | FileCheck --check-prefix=DWO %s
+
+// The .dwo file of a program compiled and linked in one step is named after
+// the program rather than the temporary object file.
+// RUN: llgo -gsplit-dwarf -o %t.linked.out %S/Inputs/split-dwarf-main.go
+// RUN: llvm-dwarfdump -debug-dump=info %t.linked.out | FileCheck --check-prefix=LINKED %s
+// RUN: llvm-dwarfdump -debug-dump=info.dwo %t.linked.dwo | FileCheck --check-prefix=DWO %s
+
+// RUN: llgo -c -g -gz -o %t.gz.o %s
+// RUN: llvm-readobj -sections %t.gz.o | FileCheck --check-prefix=GZ %s
+
+// RUN: not llgo -c -g -gz=lzma -o %t.o %s 2>&1 | FileCheck --check-prefix=BADGZ %s
+
+// SKELETON: DW_TAG_compile_unit
+// SKELETON: DW_AT_GNU_dwo_name {{.*}}"{{.*}}split-dwarf.go.tmp.dwo"
+// SKELETON-NOT: DW_TAG_subprogram
+
+// LINKED: DW_AT_GNU_dwo_name {{.*}}"{{.*}}split-dwarf.go.tmp.linked.dwo"
+
+// DWO: DW_TAG_compile_unit
+// DWO: DW_TAG_subprogram
+// DWO: DW_AT_name {{.*}}"{{.*}}F"
+
+// GZ: Name: .debug_info
+// GZ-NEXT: Type:
+// GZ-NEXT: Flags [
+// GZ: SHF_COMPRESSED
+
+// BADGZ: unsupported argument 'lzma' to option 'gz'
+
+package foo
+
+func F() int {
+	return 1
+}
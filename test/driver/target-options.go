@@ -0,0 +1,19 @@
+// RUN: not llgo -c -march=bogus -o %t.o %s 2>&1 | FileCheck --check-prefix=MARCH %s
+// RUN: not llgo -c -mtune=bogus -o %t.o %s 2>&1 | FileCheck --check-prefix=MTUNE %s
+// RUN: not llgo -c -mfoo -o %t.o %s 2>&1 | FileCheck --check-prefix=MFOO %s
+// RUN: not llgo -c -mno-foo -o %t.o %s 2>&1 | FileCheck --check-prefix=MNOFOO %s
+// RUN: not llgo -c -mno-red-zone -o %t.o %s 2>&1 | FileCheck --check-prefix=REDZONE %s
+// RUN: not llgo -c -march=native -o %t.o %s 2>&1 | FileCheck --check-prefix=NATIVE %s
+
+// MARCH: invalid CPU name 'bogus' for target
+// MTUNE: invalid CPU name 'bogus' for target
+// MFOO: unrecognized command line option '-mfoo' for target
+// MNOFOO: unrecognized command line option '-mno-foo' for target
+
+// Code generation options that GCC implements as -m flags, but that are not
+// LLVM target features, are rejected too.
+// REDZONE: unrecognized command line option '-mno-red-zone' for target
+
+// NATIVE: 'native' is not a supported CPU
+
+package foo
@@ -0,0 +1,85 @@
+// RUN: llgo -o %t %s
+// RUN: %t 2>&1 | FileCheck %s
+
+// CHECK: 42
+// CHECK-NEXT: recovered: -1
+// CHECK-NEXT: deref deferred
+// CHECK-NEXT: recovered: true
+// CHECK-NEXT: recovered: in defer
+// CHECK-NEXT: goexit deferred
+// CHECK-NEXT: done
+
+package main
+
+import "runtime"
+
+// double's defer may be open-coded.
+func double(n int) (r int) {
+	defer func() { r = n * 2 }()
+	return n
+}
+
+func recovers() (r int) {
+	defer func() {
+		if e := recover(); e != nil {
+			r = -1
+		}
+	}()
+	panic("boom")
+}
+
+func deref(p *int) int {
+	defer func() { println("deref deferred") }()
+	return *p
+}
+
+func recoverDeref() {
+	defer func() {
+		println("recovered:", recover() != nil)
+	}()
+	deref(nil)
+	println("not reached")
+}
+
+// panicInDefer's defer may be open-coded.
+func panicInDefer() {
+	defer func() { panic("in defer") }()
+}
+
+func recoverPanicInDefer() {
+	defer func() {
+		println("recovered:", recover().(string))
+	}()
+	panicInDefer()
+	println("not reached")
+}
+
+func exit() {
+	runtime.Goexit()
+}
+
+// exitInDefer's defer may be open-coded.
+func exitInDefer() {
+	defer exit()
+}
+
+func goexit(done chan bool) {
+	defer func() {
+		println("goexit deferred")
+		done <- true
+	}()
+	exitInDefer()
+	println("not reached")
+}
+
+func main() {
+	println(double(21))
+	println("recovered:", recovers())
+	recoverDeref()
+	recoverPanicInDefer()
+
+	done := make(chan bool)
+	go goexit(done)
+	<-done
+	println("done")
+}
@@ -0,0 +1,53 @@
+// RUN: llgo -o %t %s
+// RUN: %t 2>&1 | FileCheck %s
+
+// CHECK: 3 true
+// CHECK-NEXT: 0 false
+// CHECK-NEXT: 2
+// CHECK-NEXT: 7 true
+// CHECK-NEXT: 0 false
+// CHECK-NEXT: 1
+// CHECK-NEXT: 1 true
+// CHECK-NEXT: 0 false
+
+package main
+
+func main() {
+	// Entries inserted through the specialized entry points must be
+	// found by the generic ones, which are used for delete and range.
+	m := make(map[int64]int)
+	for i := int64(0); i < 100; i++ {
+		m[i<<32] = int(i)
+	}
+	for i := int64(0); i < 100; i += 2 {
+		delete(m, i<<32)
+	}
+	v, ok := m[3<<32]
+	println(v, ok)
+	v, ok = m[4<<32]
+	println(v, ok)
+	n := 0
+	for k := range m {
+		if k == 3<<32 || k == 5<<32 {
+			n++
+		}
+	}
+	println(n)
+
+	sm := make(map[string]int)
+	sm["hello"] = 7
+	sm["world"] = 8
+	delete(sm, string([]byte("world")))
+	sv, ok := sm[string([]byte("hello"))]
+	println(sv, ok)
+	sv, ok = sm["world"]
+	println(sv, ok)
+	println(len(sm))
+
+	x, y := new(int), new(int)
+	pm := map[*int]int{x: 1}
+	pv, ok := pm[x]
+	println(pv, ok)
+	pv, ok = pm[y]
+	println(pv, ok)
+}
@@ -0,0 +1,28 @@
+// RUN: llgo -o %t %s
+// RUN: %t 2>&1 | FileCheck %s
+
+// CHECK: true true true
+// CHECK-NEXT: true true true
+// CHECK-NEXT: -2.000000e+000 -1.000000e+000 -1.000000e+000
+// CHECK-NEXT: true true true
+// CHECK-NEXT: true true true
+// CHECK-NEXT: true false true
+// CHECK-NEXT: true -3.000000e+000
+
+package main
+
+import "math"
+
+// The functions of the math package that are lowered to intrinsics must
+// keep their special cases for NaNs, infinities and signed zeros.
+
+func main() {
+	zero, negzero, nan, inf := 0.0, math.Copysign(0, -1), math.NaN(), math.Inf(1)
+	println(math.IsNaN(math.Sqrt(-1)), math.IsNaN(math.Sqrt(nan)), math.IsNaN(math.Sqrt(-inf)))
+	println(math.Signbit(math.Sqrt(negzero)), math.Sqrt(zero) == 0, math.IsInf(math.Sqrt(inf), 1))
+	println(math.Floor(-1.5), math.Ceil(-1.5), math.Trunc(-1.5))
+	println(math.Signbit(math.Floor(negzero)), math.Signbit(math.Ceil(-0.5)), math.Signbit(math.Trunc(-0.5)))
+	println(math.IsNaN(math.Floor(nan)), math.IsNaN(math.Ceil(nan)), math.IsNaN(math.Trunc(nan)))
+	println(math.Abs(negzero) == 0, math.Signbit(math.Abs(negzero)), math.IsNaN(math.Abs(nan)))
+	println(math.Signbit(math.Copysign(nan, -1)), math.Copysign(3, negzero))
+}
@@ -0,0 +1,15 @@
+package p
+
+type Buffer struct {
+	buf []byte
+	off int
+}
+
+func (b *Buffer) Len() int { return len(b.buf) - b.off }
+
+var counter int
+
+func Count() int {
+	counter++
+	return counter
+}
@@ -0,0 +1,44 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// CHECK-LABEL: define i32 @foo.Add
+// CHECK: %[[OLD:.*]] = atomicrmw add i32* {{.*}}, i32 %{{.*}} seq_cst
+// CHECK-NEXT: %[[NEW:.*]] = add i32 %[[OLD]], %{{.*}}
+// CHECK: ret i32 %[[NEW]]
+func Add(p *int32, d int32) int32 {
+	return atomic.AddInt32(p, d)
+}
+
+// CHECK-LABEL: define i8 @foo.CAS
+// CHECK: %[[PAIR:.*]] = cmpxchg i64* {{.*}}, i64 %{{.*}}, i64 %{{.*}} seq_cst seq_cst
+// CHECK-NEXT: %[[OK:.*]] = extractvalue { i64, i1 } %[[PAIR]], 1
+// CHECK-NEXT: zext i1 %[[OK]] to i8
+func CAS(p *uint64, old, new uint64) bool {
+	return atomic.CompareAndSwapUint64(p, old, new)
+}
+
+// CHECK-LABEL: define i32 @foo.Load
+// CHECK: load atomic i32, i32* {{.*}} seq_cst, align 4
+func Load(p *uint32) uint32 {
+	return atomic.LoadUint32(p)
+}
+
+// CHECK-LABEL: define void @foo.Store
+// CHECK: store atomic i64 %{{.*}}, i64* {{.*}} seq_cst, align 8
+func Store(p *int64, v int64) {
+	atomic.StoreInt64(p, v)
+}
+
+// CHECK-LABEL: define i8* @foo.SwapPointer
+// CHECK: ptrtoint i8* %{{.*}} to i64
+// CHECK: %[[OLD:.*]] = atomicrmw xchg i64* {{.*}}, i64 %{{.*}} seq_cst
+// CHECK-NEXT: inttoptr i64 %[[OLD]] to i8*
+func SwapPointer(p *unsafe.Pointer, v unsafe.Pointer) unsafe.Pointer {
+	return atomic.SwapPointer(p, v)
+}
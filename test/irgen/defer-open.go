@@ -0,0 +1,48 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+type M struct{ n int }
+
+func (m *M) Lock()   { m.n++ }
+func (m *M) Unlock() { m.n-- }
+
+// A defer that executes at most once, in a function which cannot otherwise
+// panic, is open-coded: the deferred call is marked pending in a stack slot
+// and run directly on return.
+
+// CHECK-LABEL: define i64 @foo.Open
+// CHECK: store i1 false, i1* [[FLAG:%[0-9]+]]
+// CHECK: store i1 true, i1* [[FLAG]]
+// CHECK: load i1, i1* [[FLAG]]
+// CHECK: invoke void @{{[0-9]+}}(i8*
+// CHECK-LABEL: define
+func Open(n int) (r int) {
+	defer func() { r = n * 2 }()
+	return n
+}
+
+// Any other call may panic, and the runtime runs the deferred calls of a
+// panicking goroutine before unwinding, so the defer must be registered.
+
+// CHECK-LABEL: define void @foo.Call
+// CHECK: invoke void @__go_defer
+// CHECK: invoke void @__go_undefer
+// CHECK-LABEL: define
+func Call(m *M) {
+	m.Lock()
+	defer m.Unlock()
+}
+
+// Defers in loops use the runtime's defer chain.
+
+// CHECK-LABEL: define void @foo.Loop
+// CHECK: invoke void @__go_defer
+// CHECK: invoke void @__go_undefer
+// CHECK-LABEL: define
+func Loop(ms []*M) {
+	for _, m := range ms {
+		m.Lock()
+		defer m.Unlock()
+	}
+}
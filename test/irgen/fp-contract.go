@@ -0,0 +1,70 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=OFF %s
+// RUN: llgo -S -emit-llvm -ffp-contract=on -o - %s | FileCheck --check-prefix=ON %s
+// RUN: llgo -S -emit-llvm -g -ffp-contract=on -o - %s | FileCheck --check-prefix=ON %s
+// RUN: llgo -S -emit-llvm -ffp-contract=fast -o - %s | FileCheck --check-prefix=FAST %s
+// RUN: llgo -S -emit-llvm -g -ffp-contract=fast -o - %s | FileCheck --check-prefix=FAST %s
+// RUN: llgo -S -emit-llvm -ffast-math -o - %s | FileCheck --check-prefix=FASTMATH %s
+// RUN: llgo -S -emit-llvm -funsafe-math-optimizations -o - %s | FileCheck --check-prefix=UNSAFE %s
+// RUN: llgo -S -emit-llvm -funsafe-math-optimizations -fno-fast-math -o - %s | FileCheck --check-prefix=NOFAST %s
+// RUN: llgo -S -emit-llvm -ffp-contract=fast -fno-fast-math -o - %s | FileCheck --check-prefix=FAST %s
+
+package foo
+
+// OFF-LABEL: define double @foo.MulAdd
+// OFF: fmul double
+// OFF: fadd double
+// OFF-NOT: @llvm.fmuladd
+
+// ON-LABEL: define double @foo.MulAdd
+// ON-NOT: fmul
+// ON: call double @llvm.fmuladd.f64(double %{{.*}}, double %{{.*}}, double %{{.*}})
+func MulAdd(a, b, c float64) float64 {
+	return a*b + c
+}
+
+// ON-LABEL: define float @foo.SubMul
+// ON: %[[NEG:.*]] = fsub float -0.000000e+00, %{{.*}}
+// ON: call float @llvm.fmuladd.f32(float %[[NEG]], float %{{.*}}, float %{{.*}})
+func SubMul(a, b, c float32) float32 {
+	return c - a*b
+}
+
+// A multiplication with several uses is only contracted in fast mode.
+
+// ON-LABEL: define double @foo.Shared
+// ON: fmul double
+// ON-NOT: @llvm.fmuladd
+
+// FAST-LABEL: define double @foo.Shared
+// FAST-NOT: fmul
+// FAST: @llvm.fmuladd.f64
+// FAST: @llvm.fmuladd.f64
+func Shared(a, b, c, d float64) float64 {
+	x := a * b
+	return (x + c) / (x - d)
+}
+
+// Floating-point arithmetic carries the fast-math flags allowed.
+
+// FASTMATH-LABEL: define double @foo.Div
+// FASTMATH: fsub fast double
+// FASTMATH: fdiv fast double
+
+// UNSAFE-LABEL: define double @foo.Div
+// UNSAFE: fsub nsz arcp double
+// UNSAFE: fdiv nsz arcp double
+
+// NOFAST-LABEL: define double @foo.Div
+// NOFAST: fsub double
+// NOFAST: fdiv double
+func Div(a, b, c float64) float64 {
+	return (a - c) / b
+}
+
+// FAST: "less-precise-fpmad"="true"
+
+// FASTMATH: "less-precise-fpmad"="true"
+// FASTMATH-SAME: "no-infs-fp-math"="true"
+// FASTMATH-SAME: "no-nans-fp-math"="true"
+// FASTMATH-SAME: "no-signed-zeros-fp-math"="true"
+// FASTMATH-SAME: "unsafe-fp-math"="true"
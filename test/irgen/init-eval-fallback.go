@@ -0,0 +1,16 @@
+// RUN: llgo -finit-eval -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// Initializers with side effects are run at program start.
+
+// CHECK: @foo.X = global i64 0
+var X = compute()
+
+func compute() int {
+	println("computing")
+	return 42
+}
+
+// CHECK-LABEL: define void @foo..import
+// CHECK: call {{.*}} @foo.compute
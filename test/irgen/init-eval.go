@@ -0,0 +1,32 @@
+// RUN: llgo -finit-eval -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -fno-init-eval -S -emit-llvm -o - %s | FileCheck --check-prefix=NOEVAL %s
+
+package foo
+
+// CHECK: @foo.Table = global { i8*, i64, i64 } { i8* bitcast ([8 x i64]* [[ARR:@[0-9]+]] to i8*), i64 8, i64 8 }
+// CHECK: @foo.Sub = global { i8*, i64, i64 } { i8* bitcast (i64* getelementptr inbounds ([8 x i64], [8 x i64]* [[ARR]], i32 0, i32 2) to i8*), i64 2, i64 6 }
+// CHECK: [[ARR]] = internal global [8 x i64] [i64 0, i64 1, i64 4, i64 9, i64 16, i64 25, i64 36, i64 49]
+var Table = buildTable()
+var Sub = Table[2:4]
+
+func buildTable() []int {
+	r := make([]int, 8)
+	for i := range r {
+		r[i] = i * i
+	}
+	return r
+}
+
+// Nil maps and channels do not prevent evaluation.
+var Registry struct {
+	byName map[string]int
+	done   chan bool
+	count  int
+}
+
+// CHECK-LABEL: define void @foo..import
+// CHECK-NOT: @foo.buildTable
+// CHECK: ret void
+
+// NOEVAL-LABEL: define void @foo..import
+// NOEVAL: call {{.*}} @foo.buildTable
@@ -0,0 +1,14 @@
+// RUN: llgo -fgo-pkgpath=p -finline-limit=30 -c -o %T/p.o %S/Inputs/inline-import-p.go
+// RUN: llgo -fgo-pkgpath=q -finline-limit=30 -I %T -S -emit-llvm -o - %s | FileCheck %s
+
+package q
+
+import "p"
+
+// CHECK-DAG: define available_externally i64 @p.Buffer.Len
+
+// Functions referring to unexported variables are not exported for inlining.
+// CHECK-DAG: declare {{.*}} @p.Count
+func F(b *p.Buffer) int {
+	return b.Len() + p.Count()
+}
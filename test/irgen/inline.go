@@ -0,0 +1,35 @@
+// RUN: llgo -finline-limit=30 -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -fno-inline -S -emit-llvm -o - %s | FileCheck --check-prefix=NOINLINE %s
+
+package foo
+
+type T struct{ x, y int }
+
+func (t *T) Sum() int {
+	return t.x + t.y
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func deferred() int {
+	defer func() {}()
+	return 1
+}
+
+// CHECK-LABEL: define i64 @foo.F
+// CHECK-NOT: call {{.*}} @foo.T.Sum
+// CHECK-NOT: call {{.*}} @foo.abs
+// CHECK: call {{.*}} @foo.deferred
+// CHECK: ret i64
+
+// NOINLINE-LABEL: define i64 @foo.F
+// NOINLINE: call {{.*}} @foo.T.Sum
+// NOINLINE: call {{.*}} @foo.abs
+func F(t *T) int {
+	return abs(t.Sum()) + deferred()
+}
@@ -0,0 +1,35 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK-LABEL: define {{.*}} @foo.Int32
+// CHECK: call i8* @__go_map_index_fast32(i8* %{{.*}}, i32 %{{.*}}, i8 {{.*}}0)
+func Int32(m map[int32]int, k int32) int {
+	return m[k]
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Int
+// CHECK: call i8* @__go_map_index_fast64(i8* %{{.*}}, i64 %{{.*}}, i8 {{.*}}1)
+func Int(m map[int]int, k int) {
+	m[k] = 1
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Pointer
+// CHECK: %[[K:.*]] = ptrtoint i8* %{{.*}} to i64
+// CHECK: call i8* @__go_map_index_fast64(i8* %{{.*}}, i64 %[[K]], i8 {{.*}}0)
+func Pointer(m map[*int]bool, k *int) bool {
+	return m[k]
+}
+
+// CHECK-LABEL: define {{.*}} @foo.String
+// CHECK: call i8* @__go_map_index_faststr(i8* %{{.*}}, i8* %{{.*}}, i64 %{{.*}}, i8 {{.*}}0)
+func String(m map[string]int, k string) (int, bool) {
+	v, ok := m[k]
+	return v, ok
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Float
+// CHECK: call i8* @__go_map_index(i8* %{{.*}}, i8* {{.*}}, i8 {{.*}}0)
+func Float(m map[float64]int, k float64) int {
+	return m[k]
+}
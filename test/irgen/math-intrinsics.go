@@ -0,0 +1,51 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+import "math"
+
+// CHECK-LABEL: define double @foo.Sqrt
+// CHECK: %[[NEG:.*]] = fcmp olt double %{{.*}}, 0.000000e+00
+// CHECK-NEXT: %[[X:.*]] = select i1 %[[NEG]], double 0x7FF8000000000001, double %{{.*}}
+// CHECK-NEXT: call double @llvm.sqrt.f64(double %[[X]])
+func Sqrt(x float64) float64 {
+	return math.Sqrt(x)
+}
+
+// CHECK-LABEL: define double @foo.Abs
+// CHECK: call double @llvm.fabs.f64(double %{{.*}})
+func Abs(x float64) float64 {
+	return math.Abs(x)
+}
+
+// CHECK-LABEL: define double @foo.Floor
+// CHECK: call double @llvm.floor.f64(double %{{.*}})
+func Floor(x float64) float64 {
+	return math.Floor(x)
+}
+
+// CHECK-LABEL: define double @foo.Copysign
+// CHECK: call double @llvm.copysign.f64(double %{{.*}}, double %{{.*}})
+func Copysign(x, y float64) float64 {
+	return math.Copysign(x, y)
+}
+
+// CHECK-LABEL: define double @foo.Ceil
+// CHECK: call double @llvm.ceil.f64(double %{{.*}})
+func Ceil(x float64) float64 {
+	return math.Ceil(x)
+}
+
+// CHECK-LABEL: define double @foo.Trunc
+// CHECK: call double @llvm.trunc.f64(double %{{.*}})
+func Trunc(x float64) float64 {
+	return math.Trunc(x)
+}
+
+// The operand of llvm.sqrt is replaced by NaN if it is negative.
+
+// CHECK-LABEL: define double @foo.SqrtNeg
+// CHECK: call double @llvm.sqrt.f64(double 0x7FF8000000000001)
+func SqrtNeg() float64 {
+	return math.Sqrt(-1)
+}
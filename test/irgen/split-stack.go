@@ -0,0 +1,19 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+// RUN: llgo -S -emit-llvm -fno-split-stack -o - %s | FileCheck --check-prefix=NOSPLIT %s
+// RUN: llgo -S -emit-llvm -foptimize-sibling-calls -o - %s | FileCheck --check-prefix=SIBCALL %s
+// RUN: llgo -fno-split-stack -print-multi-os-directory | FileCheck --check-prefix=LIBDIR %s
+
+package foo
+
+func F() {}
+
+// DEFAULT: attributes #{{[0-9]+}} = { {{.*}}"disable-tail-calls"="true"
+// DEFAULT-SAME: "split-stack"
+
+// NOSPLIT: attributes #{{[0-9]+}} = { {{.*}}"disable-tail-calls"="true"
+// NOSPLIT-NOT: split-stack
+
+// SIBCALL-NOT: disable-tail-calls
+// SIBCALL: "split-stack"
+
+// LIBDIR: llvm-nosplit.0
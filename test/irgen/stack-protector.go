@@ -0,0 +1,20 @@
+// RUN: llgo -S -emit-llvm -fstack-protector -o - %s | FileCheck --check-prefix=SSP %s
+// RUN: llgo -S -emit-llvm -fstack-protector-strong -o - %s | FileCheck --check-prefix=STRONG %s
+// RUN: llgo -S -emit-llvm -fstack-protector-all -o - %s | FileCheck --check-prefix=ALL %s
+// RUN: llgo -S -emit-llvm -fstack-protector-all -fno-stack-protector -o - %s | FileCheck --check-prefix=NONE %s
+// RUN: llgo -S -fstack-protector-strong -o - %s | FileCheck --check-prefix=ASM %s
+
+package foo
+
+// SSP: attributes #{{[0-9]+}} = { {{.*}}ssp{{ |$}}
+// STRONG: attributes #{{[0-9]+}} = { {{.*}}sspstrong
+// ALL: attributes #{{[0-9]+}} = { {{.*}}sspreq
+// NONE-NOT: ssp
+
+// ASM-LABEL: foo.Index:
+// ASM: __stack_chk_fail
+func Index(i int) byte {
+	var a [64]byte
+	a[i] = 1
+	return a[i]
+}
@@ -0,0 +1,58 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -g -o - %s | FileCheck %s
+
+package foo
+
+// CHECK-LABEL: define {{.*}} @foo.Join
+// CHECK-NOT: @__go_string_plus
+// CHECK: alloca [4 x { i8*, i64 }]
+// CHECK-NOT: @__go_string_plus
+// CHECK: call {{.*}} @__go_string_concat(
+// CHECK-NOT: @__go_string_plus
+// CHECK: ret
+func Join(a, b string) string {
+	return a + ":" + b + "\n"
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Plus
+// CHECK: call {{.*}} @__go_string_plus
+func Plus(a, b string) string {
+	return a + b
+}
+
+// Each iteration of a loop appending to a string concatenates once.
+
+// CHECK-LABEL: define {{.*}} @foo.Lines
+// CHECK-NOT: @__go_string_plus
+// CHECK: alloca [3 x { i8*, i64 }]
+// CHECK-NOT: @__go_string_plus
+// CHECK: call {{.*}} @__go_string_concat(
+// CHECK-NOT: @__go_string_plus
+// CHECK: ret
+func Lines(lines []string) string {
+	var s string
+	for _, line := range lines {
+		s += line + "\n"
+	}
+	return s
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Lookup
+// CHECK-NOT: @__go_new_nopointers
+// CHECK: call {{.*}} @__go_map_index
+func Lookup(m map[string]int, b []byte) int {
+	return m[string(b)]
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Equal
+// CHECK-NOT: @__go_new_nopointers
+// CHECK: call {{.*}} @__go_strcmp
+func Equal(s string, b []byte) bool {
+	return string(b) == s
+}
+
+// CHECK-LABEL: define {{.*}} @foo.Convert
+// CHECK: call {{.*}} @__go_new_nopointers
+func Convert(b []byte) string {
+	return string(b)
+}
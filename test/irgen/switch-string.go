@@ -0,0 +1,60 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK-LABEL: define {{.*}} @foo.Method
+// CHECK-NOT: @__go_strcmp
+// CHECK: switch i64 %{{.*}}, label %[[NOMATCH:.*]] [
+// CHECK-NEXT: i64 0, label
+// CHECK-NEXT: i64 3, label
+// CHECK-NEXT: i64 4, label
+// CHECK-NEXT: i64 5, label
+// CHECK-NEXT: i64 6, label
+// CHECK-NEXT: ]
+// CHECK: switch i8 %{{.*}}, label %[[NOMATCH]] [
+// CHECK-NEXT: i8 71, label
+// CHECK-NEXT: i8 80, label
+// CHECK-NEXT: ]
+// CHECK: call i32 @memcmp(i8* %{{.*}}, i8* {{.*}}, i64 3)
+// CHECK: phi i32
+// CHECK: switch i32 %{{.*}}, label %{{.*}} [
+// CHECK-NEXT: i32 0, label
+// CHECK-NEXT: i32 1, label
+// CHECK-NEXT: i32 2, label
+// CHECK-NEXT: i32 3, label
+// CHECK-NEXT: i32 4, label
+// CHECK-NEXT: i32 5, label
+// CHECK-NEXT: ]
+// CHECK-NOT: @__go_strcmp
+// CHECK: ret
+func Method(s string) int {
+	switch s {
+	case "GET":
+		return 1
+	case "PUT":
+		return 2
+	case "POST":
+		return 3
+	case "DELETE":
+		return 4
+	case "":
+		return 5
+	case "PATCH":
+		return 6
+	}
+	return 0
+}
+
+// Switches with few cases are still lowered to comparisons.
+
+// CHECK-LABEL: define {{.*}} @foo.Small
+// CHECK: @__go_strcmp
+func Small(s string) int {
+	switch s {
+	case "a":
+		return 1
+	case "b":
+		return 2
+	}
+	return 0
+}
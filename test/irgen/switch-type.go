@@ -0,0 +1,49 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+type T struct{ x int }
+
+// CHECK-LABEL: define {{.*}} @foo.Kind
+// CHECK: getelementptr inbounds %commonType, %commonType* %{{.*}}, i32 0, i32 4
+// CHECK: switch i32 %{{.*}}, label %[[NOMATCH:.*]] [
+// CHECK-NEXT: i32 {{-?[0-9]+}}, label
+// CHECK-NEXT: i32 {{-?[0-9]+}}, label
+// CHECK-NEXT: i32 {{-?[0-9]+}}, label
+// CHECK-NEXT: i32 {{-?[0-9]+}}, label
+// CHECK-NEXT: i32 {{-?[0-9]+}}, label
+// CHECK-NEXT: ]
+// CHECK: @__go_type_descriptors_equal
+// CHECK: ret
+func Kind(x interface{}) int {
+	switch x := x.(type) {
+	case int:
+		return x
+	case string:
+		return len(x)
+	case float64:
+		return 3
+	case *T:
+		return x.x
+	case T:
+		return x.x
+	case error:
+		return 6
+	}
+	return 0
+}
+
+// Type switches with few concrete cases are still lowered to type checks.
+
+// CHECK-LABEL: define {{.*}} @foo.Small
+// CHECK-NOT: switch i32
+// CHECK: ret
+func Small(x interface{}) int {
+	switch x.(type) {
+	case int:
+		return 1
+	case string:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,17 @@
+// RUN: llgo -S -emit-llvm -march=haswell -mtune=skylake -mavx2 -mno-sse4a -o - %s | FileCheck %s
+// RUN: not llgo -S -emit-llvm -march=has/well -o - %s 2>&1 | FileCheck --check-prefix=BADCPU %s
+// RUN: not llgo -S -emit-llvm -mno- -o - %s 2>&1 | FileCheck --check-prefix=BADFEATURE %s
+
+package foo
+
+// CHECK: define void @foo.F() {{.*}}#[[ATTRS:[0-9]+]]
+func F() {}
+
+// CHECK: attributes #[[ATTRS]] = {{.*}}"target-cpu"="haswell"
+// CHECK-SAME: "target-features"="+avx2,-sse4a"
+
+// -mtune is accepted but ignored.
+// CHECK-NOT: "tune-cpu"
+
+// BADCPU: invalid CPU name 'has/well'
+// BADFEATURE: unrecognized command line option '-mno-'
@@ -0,0 +1,30 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=ALL %s
+// RUN: llgo -fwhole-program -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -fwhole-program -S -emit-llvm -o - %s | FileCheck --check-prefix=REMOVED %s
+
+package main
+
+type I interface {
+	used()
+}
+
+type T struct{}
+
+// ALL-DAG: define {{.*}} @main.used.N4_main.T(
+// CHECK-DAG: define {{.*}} @main.used.N4_main.T(
+func (T) used() {}
+
+// The CHECK-NOT has a prefix of its own, so that it covers the whole output.
+// ALL-DAG: define {{.*}} @main.unused.N4_main.T(
+// REMOVED-NOT: @main.unused.N4_main.T
+func (T) unused() {}
+
+// Exported methods of runtime types may be called via reflection.
+// ALL-DAG: define {{.*}} @main.Exported.N4_main.T(
+// CHECK-DAG: define {{.*}} @main.Exported.N4_main.T(
+func (T) Exported() {}
+
+func main() {
+	var i I = T{}
+	i.used()
+}
@@ -0,0 +1,46 @@
+// RUN: llgo -S -emit-llvm -o - %s | llgo-symbolize | FileCheck %s
+
+package foo
+
+type T struct{ n int }
+
+func F() int { return 1 }
+
+func (t *T) M() int { return t.n }
+
+func (t T) V() int { return t.n }
+
+func (t *T) Closure() func() int {
+	return func() int {
+		return t.n
+	}
+}
+
+func Outer() func() func() int {
+	return func() func() int {
+		return func() int {
+			return 2
+		}
+	}
+}
+
+func MethodValue(t *T) func() int {
+	return t.M
+}
+
+func MethodExpr() func(*T) int {
+	return (*T).M
+}
+
+func init() {}
+
+// CHECK-DAG: define {{.*}}@foo.F(
+// CHECK-DAG: define {{.*}}@foo.(*T).M(
+// CHECK-DAG: define {{.*}}@foo.T.V(
+// CHECK-DAG: define {{.*}}@"foo.(*T).Closure.func1"(
+// CHECK-DAG: define {{.*}}@"foo.Outer.func1"(
+// CHECK-DAG: define {{.*}}@"foo.Outer.func1.1"(
+// CHECK-DAG: define {{.*}}@"foo.(*T).M-fm"(
+// CHECK-DAG: define {{.*}}@"foo.(*T).M"(
+// CHECK-DAG: define {{.*}}@foo.init(
+// CHECK-DAG: define {{.*}}@"foo.init#1"(
@@ -71,6 +71,37 @@ __go_map_rehash (struct __go_map *map)
   map->__buckets = new_buckets;
 }
 
+/* Insert KEY, whose hash code is KEY_HASH, into MAP, which must not
+   already contain it.  Return a pointer to the new value, which is
+   zero-initialized.  */
+
+static void *
+__go_map_insert (struct __go_map *map, const void *key, size_t key_size,
+		 size_t key_hash)
+{
+  const struct __go_map_descriptor *descriptor;
+  size_t bucket_index;
+  char *entry;
+
+  descriptor = map->__descriptor;
+
+  if (map->__element_count >= map->__bucket_count)
+    __go_map_rehash (map);
+  bucket_index = key_hash % map->__bucket_count;
+
+  entry = (char *) __go_alloc (descriptor->__entry_size);
+  __builtin_memset (entry, 0, descriptor->__entry_size);
+
+  __builtin_memcpy (entry + descriptor->__key_offset, key, key_size);
+
+  *(char **) entry = map->__buckets[bucket_index];
+  map->__buckets[bucket_index] = entry;
+
+  map->__element_count += 1;
+
+  return entry + descriptor->__val_offset;
+}
+
 /* Find KEY in MAP, return a pointer to the value.  If KEY is not
    present, then if INSERT is false, return NULL, and if INSERT is
    true, insert a new value and zero-initialize it before returning a
@@ -117,21 +148,112 @@ __go_map_index (struct __go_map *map, const void *key, _Bool insert)
   if (!insert)
     return NULL;
 
-  if (map->__element_count >= map->__bucket_count)
+  return __go_map_insert (map, key, key_size, key_hash);
+}
+
+/* The following are versions of __go_map_index specialized for maps
+   whose keys are 32-bit or 64-bit integers or pointers, or strings.
+   They must compute the same hash codes as the key type's hash
+   function, __go_type_hash_identity or __go_type_hash_string, as a
+   map may be accessed through either entry point.  */
+
+void *
+__go_map_index_fast32 (struct __go_map *map, uint32 key, _Bool insert)
+{
+  const struct __go_map_descriptor *descriptor;
+  size_t key_hash;
+  char *entry;
+
+  if (map == NULL)
     {
-      __go_map_rehash (map);
-      bucket_index = key_hash % map->__bucket_count;
+      if (insert)
+	runtime_panicstring ("assignment to entry in nil map");
+      return NULL;
     }
 
-  entry = (char *) __go_alloc (descriptor->__entry_size);
-  __builtin_memset (entry, 0, descriptor->__entry_size);
+  descriptor = map->__descriptor;
+  key_hash = (uintptr_t) key;
+  entry = (char *) map->__buckets[key_hash % map->__bucket_count];
+  while (entry != NULL)
+    {
+      if (*(const uint32 *) (entry + descriptor->__key_offset) == key)
+	return entry + descriptor->__val_offset;
+      entry = *(char **) entry;
+    }
 
-  __builtin_memcpy (entry + key_offset, key, key_size);
+  if (!insert)
+    return NULL;
 
-  *(char **) entry = map->__buckets[bucket_index];
-  map->__buckets[bucket_index] = entry;
+  return __go_map_insert (map, &key, sizeof key, key_hash);
+}
 
-  map->__element_count += 1;
+void *
+__go_map_index_fast64 (struct __go_map *map, uint64 key, _Bool insert)
+{
+  const struct __go_map_descriptor *descriptor;
+  size_t key_hash;
+  char *entry;
 
-  return entry + descriptor->__val_offset;
+  if (map == NULL)
+    {
+      if (insert)
+	runtime_panicstring ("assignment to entry in nil map");
+      return NULL;
+    }
+
+  descriptor = map->__descriptor;
+  if (sizeof (uintptr_t) >= 8)
+    key_hash = (uintptr_t) key;
+  else
+    key_hash = (uintptr_t) ((key >> 32) ^ (key & 0xffffffff));
+  entry = (char *) map->__buckets[key_hash % map->__bucket_count];
+  while (entry != NULL)
+    {
+      if (*(const uint64 *) (entry + descriptor->__key_offset) == key)
+	return entry + descriptor->__val_offset;
+      entry = *(char **) entry;
+    }
+
+  if (!insert)
+    return NULL;
+
+  return __go_map_insert (map, &key, sizeof key, key_hash);
+}
+
+void *
+__go_map_index_faststr (struct __go_map *map, String key, _Bool insert)
+{
+  const struct __go_map_descriptor *descriptor;
+  size_t key_hash;
+  intgo i;
+  char *entry;
+
+  if (map == NULL)
+    {
+      if (insert)
+	runtime_panicstring ("assignment to entry in nil map");
+      return NULL;
+    }
+
+  descriptor = map->__descriptor;
+  key_hash = 5381;
+  for (i = 0; i < key.len; i++)
+    key_hash = key_hash * 33 + key.str[i];
+  entry = (char *) map->__buckets[key_hash % map->__bucket_count];
+  while (entry != NULL)
+    {
+      const String *entry_key;
+
+      entry_key = (const String *) (entry + descriptor->__key_offset);
+      if (entry_key->len == key.len
+	  && (entry_key->str == key.str
+	      || __builtin_memcmp (entry_key->str, key.str, key.len) == 0))
+	return entry + descriptor->__val_offset;
+      entry = *(char **) entry;
+    }
+
+  if (!insert)
+    return NULL;
+
+  return __go_map_insert (map, &key, sizeof key, key_hash);
 }
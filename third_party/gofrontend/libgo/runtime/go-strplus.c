@@ -6,6 +6,7 @@
 
 #include "runtime.h"
 #include "arch.h"
+#include "array.h"
 #include "malloc.h"
 
 String
@@ -28,3 +29,48 @@ __go_string_plus (String s1, String s2)
   ret.len = len;
   return ret;
 }
+
+/* Concatenate the strings in the slice A, allocating the result
+   only once.  This is used for expressions such as a + b + c.  */
+
+String
+__go_string_concat (Slice a)
+{
+  const String *strs;
+  intgo i;
+  intgo count;
+  intgo len;
+  byte *retdata;
+  String ret;
+
+  strs = (const String *) a.__values;
+  count = 0;
+  len = 0;
+  ret.str = NULL;
+  ret.len = 0;
+  for (i = 0; i < a.__count; ++i)
+    {
+      if (strs[i].len == 0)
+	continue;
+      if (strs[i].len > (intgo) ((uintgo) -1 >> 1) - len)
+	runtime_throw ("string concatenation too long");
+      len += strs[i].len;
+      ++count;
+      ret = strs[i];
+    }
+
+  /* If at most one string is non-empty, there is nothing to copy.  */
+  if (count <= 1)
+    return ret;
+
+  retdata = runtime_mallocgc (len, 0, FlagNoScan | FlagNoZero);
+  len = 0;
+  for (i = 0; i < a.__count; ++i)
+    {
+      __builtin_memcpy (retdata + len, strs[i].str, strs[i].len);
+      len += strs[i].len;
+    }
+  ret.str = retdata;
+  ret.len = len;
+  return ret;
+}
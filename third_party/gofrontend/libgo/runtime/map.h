@@ -75,6 +75,12 @@ extern uintptr_t __go_map_next_prime (uintptr_t);
 
 extern void *__go_map_index (struct __go_map *, const void *, _Bool);
 
+extern void *__go_map_index_fast32 (struct __go_map *, uint32, _Bool);
+
+extern void *__go_map_index_fast64 (struct __go_map *, uint64, _Bool);
+
+extern void *__go_map_index_faststr (struct __go_map *, String, _Bool);
+
 extern void __go_map_delete (struct __go_map *, const void *);
 
 extern void __go_mapiterinit (const struct __go_map *, struct __go_hash_iter *);
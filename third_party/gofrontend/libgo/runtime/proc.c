@@ -7,6 +7,7 @@
 #include <stdlib.h>
 #include <pthread.h>
 #include <unistd.h>
+#include <sys/mman.h>
 
 #include "config.h"
 
@@ -53,6 +54,11 @@ extern void __splitstack_block_signals_context (void *context[10], int *,
 # define StackMin ((sizeof(char *) < 8) ? 2 * 1024 * 1024 : 4 * 1024 * 1024)
 #endif
 
+// The size of goroutine stacks.  Without split stacks, goroutine
+// stacks are of a fixed size, which may be set with the GOSTACKSIZE
+// environment variable.
+static int32 gstacksize = StackMin;
+
 uintptr runtime_stacks_sys;
 
 static void gtraceback(G*);
@@ -480,6 +486,11 @@ runtime_schedinit(void)
 			n = MaxGomaxprocs;
 		procs = n;
 	}
+#ifndef USING_SPLIT_STACK
+	p = runtime_getenv("GOSTACKSIZE");
+	if(p != nil && (n = runtime_atoi(p)) > 0)
+		gstacksize = n;
+#endif
 	runtime_allp = runtime_malloc((MaxGomaxprocs+1)*sizeof(runtime_allp[0]));
 	procresize(procs);
 
@@ -1210,7 +1221,7 @@ runtime_newextram(void)
 	// runtime.goexit makes clear to the traceback routines where
 	// the goroutine stack ends.
 	mp = runtime_allocm(nil, StackMin, &g0_sp, &g0_spsize);
-	gp = runtime_malg(StackMin, &sp, &spsize);
+	gp = runtime_malg(gstacksize, &sp, &spsize);
 	gp->status = Gdead;
 	mp->curg = gp;
 	mp->locked = LockInternal;
@@ -2151,6 +2162,28 @@ syscall_runtime_AfterFork(void)
 	runtime_m()->locks--;
 }
 
+#ifndef USING_SPLIT_STACK
+
+// Allocate a fixed-size stack from the OS, below which is an
+// inaccessible guard page so that a stack overflow faults rather than
+// overwriting other memory.
+static byte*
+allocstack(int32 stacksize)
+{
+	uintptr pagesize;
+	byte *p;
+
+	pagesize = getpagesize();
+	p = runtime_SysAlloc(ROUND(stacksize, pagesize) + pagesize, &mstats.stacks_sys);
+	if(p == nil)
+		runtime_throw("runtime: cannot allocate goroutine stack");
+	if(mprotect(p, pagesize, PROT_NONE) != 0)
+		runtime_throw("runtime: cannot protect goroutine stack guard page");
+	return p + pagesize;
+}
+
+#endif
+
 // Allocate a new g, with a stack big enough for stacksize bytes.
 G*
 runtime_malg(int32 stacksize, byte** ret_stack, size_t* ret_stacksize)
@@ -2168,7 +2201,7 @@ runtime_malg(int32 stacksize, byte** ret_stack, size_t* ret_stacksize)
 		__splitstack_block_signals_context(&newg->stack_context[0],
 						   &dont_block_signals, nil);
 #else
-		*ret_stack = runtime_mallocgc(stacksize, 0, FlagNoProfiling|FlagNoGC);
+		*ret_stack = allocstack(stacksize);
 		*ret_stacksize = stacksize;
 		newg->gcinitial_sp = *ret_stack;
 		newg->gcstack_size = stacksize;
@@ -2237,7 +2270,7 @@ __go_go(void (*fn)(void*), void* arg)
 		newg->gcnext_sp = sp;
 #endif
 	} else {
-		newg = runtime_malg(StackMin, &sp, &spsize);
+		newg = runtime_malg(gstacksize, &sp, &spsize);
 		allgadd(newg);
 	}
 
@@ -70,11 +70,13 @@ const (
 	archiveMagic    = "!<ar"
 )
 
-// Opens the export data file at the given path. If this is an ELF file,
-// searches for and opens the .go_export section. If this is an archive,
-// reads the export data from the first member, which is assumed to be an ELF file.
+// Opens the file at the given path, from which export data is read. If this
+// is a file of raw export data, it is returned as raw. Otherwise the file is
+// an ELF file, or an archive whose first member is assumed to be an ELF file,
+// and that ELF file is returned as ef. If closer is not nil, it must be closed
+// once reading is done.
 // This is intended to replicate the logic in gofrontend.
-func openExportFile(fpath string) (reader io.ReadSeeker, closer io.Closer, err error) {
+func openObjectFile(fpath string) (raw io.ReadSeeker, ef *elf.File, closer io.Closer, err error) {
 	f, err := os.Open(fpath)
 	if err != nil {
 		return
@@ -96,7 +98,7 @@ func openExportFile(fpath string) (reader io.ReadSeeker, closer io.Closer, err e
 	switch string(magic[:]) {
 	case gccgov1Magic, goimporterMagic:
 		// Raw export data.
-		reader = f
+		raw = f
 		return
 
 	case archiveMagic:
@@ -117,13 +119,25 @@ func openExportFile(fpath string) (reader io.ReadSeeker, closer io.Closer, err e
 		elfreader = f
 	}
 
-	ef, err := elf.NewFile(elfreader)
-	if err != nil {
+	ef, err = elf.NewFile(elfreader)
+	return
+}
+
+// Opens the export data file at the given path. If this is an ELF file,
+// searches for and opens the .go_export section. If this is an archive,
+// reads the export data from the first member, which is assumed to be an ELF file.
+// This is intended to replicate the logic in gofrontend.
+func openExportFile(fpath string) (reader io.ReadSeeker, closer io.Closer, err error) {
+	reader, ef, closer, err := openObjectFile(fpath)
+	if err != nil || reader != nil {
 		return
 	}
 
 	sec := ef.Section(".go_export")
 	if sec == nil {
+		if closer != nil {
+			closer.Close()
+		}
 		err = fmt.Errorf("%s: .go_export section not found", fpath)
 		return
 	}
@@ -132,6 +146,34 @@ func openExportFile(fpath string) (reader io.ReadSeeker, closer io.Closer, err e
 	return
 }
 
+// ReadSection returns the contents of the named section of the object file
+// from which the export data for pkgpath would be read. It returns nil if
+// the export data is not stored in an object file, or if the object file
+// has no such section.
+func ReadSection(searchpaths []string, pkgpath, name string) ([]byte, error) {
+	fpath, err := findExportFile(searchpaths, pkgpath)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ef, closer, err := openObjectFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	if raw != nil {
+		return nil, nil
+	}
+
+	sec := ef.Section(name)
+	if sec == nil {
+		return nil, nil
+	}
+	return sec.Data()
+}
+
 func GetImporter(searchpaths []string, initmap map[*types.Package]InitData) types.Importer {
 	return func(imports map[string]*types.Package, pkgpath string) (pkg *types.Package, err error) {
 		if pkgpath == "unsafe" {
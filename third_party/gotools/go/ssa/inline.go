@@ -0,0 +1,354 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the mechanics of inlining a function at a call
+// site.  The decision of which calls to inline is left to clients.
+
+// CanInline reports whether the body of f may be inlined by InlineCall.
+//
+// Functions without bodies, closures over free variables, and
+// functions that use defer or recover are not inlinable: the
+// semantics of the latter depend on the identity of the calling frame.
+//
+func CanInline(f *Function) bool {
+	if len(f.Blocks) == 0 || len(f.FreeVars) > 0 || f.Recover != nil {
+		return false
+	}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *Defer, *RunDefers:
+				return false
+			case CallInstruction:
+				if b, ok := instr.Common().Value.(*Builtin); ok && b.name == "recover" {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// InlineCall replaces call with a copy of the body of its static
+// callee, and reports whether it did so.  The call is left unchanged
+// if there is no static callee, if the callee is not inlinable (see
+// CanInline), if it never returns, or if it is the calling function
+// itself.
+//
+// The block containing the call is split after it; the returns of the
+// copied body become jumps to the continuation block, in which φ-nodes
+// merge the results if there is more than one return.
+//
+func InlineCall(call *Call) bool {
+	fn := call.Parent()
+	callee := call.Call.StaticCallee()
+	if callee == nil || callee == fn || !CanInline(callee) {
+		return false
+	}
+	if _, ok := call.Call.Value.(*Function); !ok || !hasReturn(callee) {
+		return false
+	}
+	if callee.Signature.Results().Len() > 1 {
+		for _, ref := range *call.Referrers() {
+			if _, ok := ref.(*Extract); !ok {
+				return false
+			}
+		}
+	}
+
+	// Split the block after the call.
+	b := call.Block()
+	index := -1
+	for i, instr := range b.Instrs {
+		if instr == call {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		panic("call not in its block")
+	}
+	cont := fn.newBasicBlock("inline.done")
+	cont.Instrs = append(cont.Instrs, b.Instrs[index+1:]...)
+	for _, instr := range cont.Instrs {
+		instr.setBlock(cont)
+	}
+	cont.Succs = append(cont.Succs, b.Succs...)
+	for _, succ := range b.Succs {
+		succ.replacePred(b, cont)
+	}
+	for i := index; i < len(b.Instrs); i++ {
+		b.Instrs[i] = nil // aid GC
+	}
+	b.Instrs = b.Instrs[:index]
+	b.Succs = b.Succs[:0]
+
+	// Copy the callee's blocks and instructions.
+	values := make(map[Value]Value)
+	for i, p := range callee.Params {
+		values[p] = call.Call.Args[i]
+	}
+	blocks := make(map[*BasicBlock]*BasicBlock)
+	for _, cb := range callee.Blocks {
+		blocks[cb] = fn.newBasicBlock(cb.Comment)
+	}
+	for _, cb := range callee.Blocks {
+		nb := blocks[cb]
+		for _, pred := range cb.Preds {
+			nb.Preds = append(nb.Preds, blocks[pred])
+		}
+		for _, succ := range cb.Succs {
+			nb.Succs = append(nb.Succs, blocks[succ])
+		}
+		for _, instr := range cb.Instrs {
+			c := cloneInstr(instr)
+			c.setBlock(nb)
+			nb.Instrs = append(nb.Instrs, c)
+			if v, ok := instr.(Value); ok {
+				values[v] = c.(Value)
+			}
+			if alloc, ok := c.(*Alloc); ok && !alloc.Heap {
+				fn.Locals = append(fn.Locals, alloc)
+			}
+		}
+	}
+
+	// Rewrite the operands of the copies, and replace each return
+	// with a jump to the continuation.
+	var results [][]Value
+	var rands []*Value
+	for _, cb := range callee.Blocks {
+		nb := blocks[cb]
+		for i, instr := range nb.Instrs {
+			rands = instr.Operands(rands[:0])
+			for _, rand := range rands {
+				if v, ok := values[*rand]; ok {
+					*rand = v
+				}
+			}
+			if ret, ok := instr.(*Return); ok {
+				results = append(results, ret.Results)
+				jump := new(Jump)
+				jump.setBlock(nb)
+				nb.Instrs[i] = jump
+				addEdge(nb, cont)
+				continue
+			}
+			for _, rand := range rands {
+				if *rand != nil {
+					if refs := (*rand).Referrers(); refs != nil {
+						*refs = append(*refs, instr)
+					}
+				}
+			}
+		}
+	}
+
+	// Jump from the call site into the copied entry block.
+	jump := new(Jump)
+	b.emit(jump)
+	addEdge(b, blocks[callee.Blocks[0]])
+	rands = call.Operands(rands[:0])
+	for _, rand := range rands {
+		if *rand != nil {
+			if refs := (*rand).Referrers(); refs != nil {
+				*refs = removeInstr(*refs, call)
+			}
+		}
+	}
+
+	// Merge the results and replace the uses of the call.
+	sig := callee.Signature.Results()
+	merged := make([]Value, sig.Len())
+	var phis []Instruction
+	for i := range merged {
+		if len(results) == 1 {
+			merged[i] = results[0][i]
+			continue
+		}
+		phi := &Phi{Comment: "inline", Edges: make([]Value, len(results))}
+		phi.typ = sig.At(i).Type()
+		phi.setBlock(cont)
+		for j, res := range results {
+			phi.Edges[j] = res[i]
+			if refs := res[i].Referrers(); refs != nil {
+				*refs = append(*refs, phi)
+			}
+		}
+		merged[i] = phi
+		phis = append(phis, phi)
+	}
+	cont.Instrs = append(phis, cont.Instrs...)
+
+	switch len(merged) {
+	case 0:
+	case 1:
+		replaceAll(call, merged[0])
+	default:
+		for _, ref := range *call.Referrers() {
+			extract := ref.(*Extract)
+			replaceAll(extract, merged[extract.Index])
+			eb := extract.Block()
+			eb.Instrs = removeInstr(eb.Instrs, extract)
+		}
+		*call.Referrers() = nil
+	}
+
+	numberRegisters(fn)
+	buildDomTree(fn)
+	return true
+}
+
+// hasReturn reports whether f contains a return instruction.
+func hasReturn(f *Function) bool {
+	for _, b := range f.Blocks {
+		if _, ok := b.Instrs[len(b.Instrs)-1].(*Return); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneInstr returns a shallow copy of instr with no referrers and
+// with fresh copies of any operand slices.
+func cloneInstr(instr Instruction) Instruction {
+	switch instr := instr.(type) {
+	case *Alloc:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *BinOp:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Call:
+		c := *instr
+		c.referrers = nil
+		c.Call.Args = append([]Value(nil), instr.Call.Args...)
+		return &c
+	case *ChangeInterface:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *ChangeType:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Convert:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *DebugRef:
+		c := *instr
+		return &c
+	case *Extract:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Field:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *FieldAddr:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Go:
+		c := *instr
+		c.Call.Args = append([]Value(nil), instr.Call.Args...)
+		return &c
+	case *If:
+		c := *instr
+		return &c
+	case *Index:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *IndexAddr:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Jump:
+		c := *instr
+		return &c
+	case *Lookup:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *MakeChan:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *MakeClosure:
+		c := *instr
+		c.referrers = nil
+		c.Bindings = append([]Value(nil), instr.Bindings...)
+		return &c
+	case *MakeInterface:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *MakeMap:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *MakeSlice:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *MapUpdate:
+		c := *instr
+		return &c
+	case *Next:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Panic:
+		c := *instr
+		return &c
+	case *Phi:
+		c := *instr
+		c.referrers = nil
+		c.Edges = append([]Value(nil), instr.Edges...)
+		return &c
+	case *Range:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Return:
+		c := *instr
+		c.Results = append([]Value(nil), instr.Results...)
+		return &c
+	case *Select:
+		c := *instr
+		c.referrers = nil
+		c.States = make([]*SelectState, len(instr.States))
+		for i, st := range instr.States {
+			stc := *st
+			c.States[i] = &stc
+		}
+		return &c
+	case *Send:
+		c := *instr
+		return &c
+	case *Slice:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *Store:
+		c := *instr
+		return &c
+	case *TypeAssert:
+		c := *instr
+		c.referrers = nil
+		return &c
+	case *UnOp:
+		c := *instr
+		c.referrers = nil
+		return &c
+	}
+	panic("unexpected instruction: " + instr.String())
+}
@@ -0,0 +1,591 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Compile-time evaluation of package initializers.
+//
+// EvalInit runs a package's init function in a restricted mode of the
+// interpreter in which any operation with an externally visible effect
+// (I/O, goroutines, channels, calls into code without SSA bodies, access
+// to the state of other packages) abandons evaluation.  On success, the
+// final values of the package's globals are exported as a graph of
+// Objects suitable for emitting as static data.
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"unsafe"
+
+	"llvm.org/llgo/third_party/gotools/go/ssa"
+	"llvm.org/llgo/third_party/gotools/go/types"
+)
+
+// EvalLimits bounds the work performed by EvalInit.
+type EvalLimits struct {
+	MaxSteps int // maximum number of instructions to interpret
+}
+
+// An Object is a unit of statically allocated memory holding a value of
+// type Type.  Global is the package-level variable that the object
+// represents, or nil for anonymous objects (slice backing arrays, heap
+// allocations, and boxed interface values).
+//
+// Value has one of the following dynamic types, according to Type:
+//
+//	bool, int, int8, ..., uintptr, float32, float64,
+//	complex64, complex128, string	-- basic types
+//	Aggregate			-- structs and arrays
+//	Pointer				-- pointers
+//	Slice				-- slices
+//	Interface			-- interfaces
+//	*ssa.Function			-- functions (possibly nil)
+//	nil				-- maps and channels, which are always nil
+type Object struct {
+	Global *ssa.Global
+	Type   types.Type
+	Value  Value
+}
+
+// A Value is the exported representation of an interpreter value; see
+// Object for the set of possible dynamic types.
+type Value interface{}
+
+// An Aggregate holds the fields of a struct or the elements of an array.
+type Aggregate []Value
+
+// A Pointer addresses a (possibly nested) element of an Object.  Path
+// holds the field or element index at each level of aggregate.
+// The nil pointer has a nil Obj.
+type Pointer struct {
+	Obj  *Object
+	Path []int
+}
+
+// A Slice is a slice whose backing array is addressed by Ptr.
+// The nil slice has a nil Ptr.Obj.
+type Slice struct {
+	Ptr      Pointer
+	Len, Cap int
+}
+
+// An Interface is an interface value holding a value of dynamic type
+// Type.  Data addresses the value itself if Type is a pointer type, and
+// a boxed copy otherwise.  The nil interface has a nil Type.
+type Interface struct {
+	Type types.Type
+	Data Pointer
+}
+
+// InitValues is the result of a successful EvalInit.
+type InitValues struct {
+	// Globals maps each of the package's globals to the object
+	// representing it.
+	Globals map[*ssa.Global]*Object
+
+	// Objects holds every object reachable from Globals, including the
+	// globals' objects themselves, in a deterministic order.
+	Objects []*Object
+}
+
+// evalAbort is the panic value used to abandon evaluation.
+type evalAbort struct {
+	reason string
+}
+
+// evalState holds the state of an EvalInit evaluation.
+type evalState struct {
+	limits EvalLimits
+	steps  int
+}
+
+// pureExternals are the external functions that may be called during
+// evaluation, as they depend only on their arguments.
+var pureExternals = map[string]bool{
+	"bytes.Equal":          true,
+	"bytes.IndexByte":      true,
+	"math.Abs":             true,
+	"math.Exp":             true,
+	"math.Float32bits":     true,
+	"math.Float32frombits": true,
+	"math.Float64bits":     true,
+	"math.Float64frombits": true,
+	"math.Ldexp":           true,
+	"math.Log":             true,
+	"math.Min":             true,
+	"strings.IndexByte":    true,
+}
+
+func abortEval(format string, args ...interface{}) {
+	panic(evalAbort{fmt.Sprintf(format, args...)})
+}
+
+// propagateAbort re-panics if p is an evalAbort, so that recover() in the
+// interpreter's own panic handling does not swallow it.
+func propagateAbort(p interface{}) {
+	if a, ok := p.(evalAbort); ok {
+		panic(a)
+	}
+}
+
+// step is called before each instruction is interpreted.
+func (e *evalState) step(instr ssa.Instruction) {
+	e.steps++
+	if e.steps > e.limits.MaxSteps {
+		abortEval("step limit exceeded")
+	}
+
+	switch instr := instr.(type) {
+	case *ssa.Go:
+		abortEval("go statement")
+	case *ssa.MakeChan, *ssa.Send, *ssa.Select:
+		abortEval("channel operation")
+	case *ssa.UnOp:
+		if instr.Op == token.ARROW {
+			abortEval("channel operation")
+		}
+	case ssa.CallInstruction:
+		if b, ok := instr.Common().Value.(*ssa.Builtin); ok {
+			switch b.Name() {
+			case "print", "println":
+				abortEval("call to %s", b.Name())
+			case "close":
+				abortEval("channel operation")
+			}
+		}
+	}
+}
+
+// checkCall is called before fn is entered.
+func (e *evalState) checkCall(fn *ssa.Function) {
+	if fn.Parent() != nil {
+		return
+	}
+	name := fn.String()
+	if externals[name] != nil {
+		if !pureExternals[name] {
+			abortEval("call to %s", name)
+		}
+		return
+	}
+	if fn.Blocks == nil {
+		abortEval("call to %s, which has no body", name)
+	}
+}
+
+// EvalInit attempts to evaluate the init function of pkg, which must have
+// been built in ssa.BareInits mode, returning the resulting values of the
+// package's globals.  If evaluation would have an effect outside the
+// package's own globals, or exceeds limits, EvalInit returns an error
+// explaining why and the init function must be run at program start as
+// usual.
+//
+// sizes must agree with the interpreter's host on the sizes of int and
+// uintptr.
+func EvalInit(pkg *ssa.Package, sizes types.Sizes, limits EvalLimits) (result *InitValues, err error) {
+	if sizes.Sizeof(types.Typ[types.Int]) != int64(unsafe.Sizeof(int(0))) ||
+		sizes.Sizeof(types.Typ[types.Uintptr]) != int64(unsafe.Sizeof(uintptr(0))) {
+		return nil, fmt.Errorf("target word size differs from host")
+	}
+	initFn := pkg.Func("init")
+	if initFn == nil || initFn.Blocks == nil {
+		return nil, fmt.Errorf("no init function")
+	}
+
+	i := &interpreter{
+		prog:    pkg.Prog,
+		globals: make(map[ssa.Value]*value),
+		sizes:   sizes,
+		eval:    &evalState{limits: limits},
+	}
+
+	var globals []*ssa.Global
+	for _, m := range pkg.Members {
+		if g, ok := m.(*ssa.Global); ok {
+			cell := zero(deref(g.Type()))
+			i.globals[g] = &cell
+			globals = append(globals, g)
+		}
+	}
+	sort.Sort(byGlobalName(globals))
+
+	defer func() {
+		if p := recover(); p != nil {
+			result = nil
+			switch p := p.(type) {
+			case evalAbort:
+				err = fmt.Errorf("%s", p.reason)
+			case targetPanic:
+				err = fmt.Errorf("init panics: %s", toString(p.v))
+			default:
+				err = fmt.Errorf("init panics: %v", p)
+			}
+		}
+	}()
+
+	call(i, nil, token.NoPos, initFn, nil)
+
+	x := newExporter()
+	for _, g := range globals {
+		obj := &Object{Global: g, Type: deref(g.Type())}
+		x.root(i.globals[g], obj)
+	}
+	return x.finish(), nil
+}
+
+type byGlobalName []*ssa.Global
+
+func (gs byGlobalName) Len() int           { return len(gs) }
+func (gs byGlobalName) Swap(i, j int)      { gs[i], gs[j] = gs[j], gs[i] }
+func (gs byGlobalName) Less(i, j int) bool { return gs[i].Name() < gs[j].Name() }
+
+// A region is a contiguous run of interpreter cells (*value) found while
+// exporting.  A region is either owned, i.e. a global cell or the
+// contents of a struct or array held in a cell of its parent region, or
+// unowned, i.e. the backing array of a slice or the pointee of a pointer.
+// Each unowned region is later found to lie within another region, or
+// becomes an anonymous object.
+type region struct {
+	start uintptr
+	n     int
+	seq   int // discovery order, for determinism
+	owned bool
+
+	parent *region // for struct and array contents
+	index  int     // index of the cell of parent holding this region
+
+	obj     *Object // for global cells and anonymous objects
+	isArray bool    // whether the cells are elements of an array object
+
+	elem  types.Type // for unowned regions, the type of each cell
+	cells []value    // for unowned regions, the cells themselves
+
+	canon  *region // the region containing this one, if any
+	offset int     // the index of this region's first cell in canon
+}
+
+func (r *region) end() uintptr {
+	return r.start + uintptr(r.n)*unsafe.Sizeof(value(nil))
+}
+
+// loc returns the location of cell k of r.
+func (r *region) loc(k int) Pointer {
+	switch {
+	case r.canon != nil:
+		return r.canon.loc(r.offset + k)
+	case r.parent != nil:
+		p := r.parent.loc(r.index)
+		path := make([]int, len(p.Path)+1)
+		copy(path, p.Path)
+		path[len(p.Path)] = k
+		return Pointer{p.Obj, path}
+	case r.isArray:
+		return Pointer{r.obj, []int{k}}
+	default:
+		return Pointer{r.obj, nil}
+	}
+}
+
+type regionKey struct {
+	start uintptr
+	n     int
+}
+
+type exporter struct {
+	owned, unowned map[regionKey]*region
+	regions        []*region
+	globals        []*region
+	extra          []*Object // boxes and empty backing arrays
+}
+
+func newExporter() *exporter {
+	return &exporter{
+		owned:   make(map[regionKey]*region),
+		unowned: make(map[regionKey]*region),
+	}
+}
+
+func addrOf(v *value) uintptr {
+	return uintptr(unsafe.Pointer(v))
+}
+
+func (x *exporter) newRegion(start uintptr, n int) *region {
+	r := &region{start: start, n: n, seq: len(x.regions)}
+	x.regions = append(x.regions, r)
+	return r
+}
+
+// root registers the global cell addr, represented by obj.
+func (x *exporter) root(addr *value, obj *Object) {
+	r := x.newRegion(addrOf(addr), 1)
+	r.owned = true
+	r.obj = obj
+	r.cells = (*[1]value)(unsafe.Pointer(addr))[:]
+	x.owned[regionKey{r.start, 1}] = r
+	x.globals = append(x.globals, r)
+	x.discover(*addr, obj.Type, r, 0)
+}
+
+// discover registers the regions reachable from v, of type t, which is
+// held in cell k of region r.  A nil r denotes a value that is not
+// addressable, such as the contents of an interface.
+func (x *exporter) discover(v value, t types.Type, r *region, k int) {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		if t.Kind() == types.UnsafePointer {
+			abortEval("unsafe.Pointer value")
+		}
+
+	case *types.Struct:
+		s := v.(structure)
+		if len(s) == 0 {
+			return
+		}
+		cr := x.ownRegion(&s[0], len(s), r, k)
+		if cr == nil && r != nil {
+			return
+		}
+		for i := range s {
+			x.discover(s[i], t.Field(i).Type(), cr, i)
+		}
+
+	case *types.Array:
+		a := v.(array)
+		if len(a) == 0 {
+			return
+		}
+		cr := x.ownRegion(&a[0], len(a), r, k)
+		if cr == nil && r != nil {
+			return
+		}
+		for i := range a {
+			x.discover(a[i], t.Elem(), cr, i)
+		}
+
+	case *types.Slice:
+		if s := v.([]value); cap(s) != 0 {
+			x.discoverCells(s[:cap(s)], t.Elem(), true)
+		}
+
+	case *types.Pointer:
+		if p := v.(*value); p != nil {
+			x.discoverCells((*[1]value)(unsafe.Pointer(p))[:], t.Elem(), false)
+		}
+
+	case *types.Interface:
+		iv := v.(iface)
+		switch iv.t {
+		case nil:
+			return
+		case rtypeType, errorType:
+			abortEval("interface holding interpreter value")
+		}
+		if _, ok := iv.t.Underlying().(*types.Pointer); ok {
+			x.discover(iv.v, iv.t, r, k)
+		} else {
+			x.discover(iv.v, iv.t, nil, 0)
+		}
+
+	case *types.Signature:
+		if _, ok := v.(*ssa.Function); !ok {
+			abortEval("closure value")
+		}
+
+	case *types.Map:
+		if !isNilMap(v) {
+			abortEval("map value")
+		}
+
+	case *types.Chan:
+		if v.(chan value) != nil {
+			abortEval("channel value")
+		}
+	}
+}
+
+// isNilMap reports whether the map value v is nil.
+func isNilMap(v value) bool {
+	switch m := v.(type) {
+	case map[value]value:
+		return m == nil
+	case *hashmap:
+		return m == nil
+	}
+	return false
+}
+
+// ownRegion registers the n cells at addr as the contents of cell k of
+// region parent, returning the new region, or nil if the cells were
+// already registered or parent is nil.
+func (x *exporter) ownRegion(addr *value, n int, parent *region, k int) *region {
+	if parent == nil {
+		return nil
+	}
+	key := regionKey{addrOf(addr), n}
+	if _, ok := x.owned[key]; ok {
+		return nil
+	}
+	r := x.newRegion(key.start, n)
+	r.owned = true
+	r.parent = parent
+	r.index = k
+	x.owned[key] = r
+	return r
+}
+
+// discoverCells registers cells, each of type elem, as an unowned region.
+// isSlice indicates that the cells are the backing array of a slice.
+func (x *exporter) discoverCells(cells []value, elem types.Type, isSlice bool) {
+	key := regionKey{addrOf(&cells[0]), len(cells)}
+	if r, ok := x.unowned[key]; ok {
+		r.isArray = r.isArray || isSlice
+		return
+	}
+	r := x.newRegion(key.start, len(cells))
+	r.elem = elem
+	r.cells = cells
+	r.isArray = isSlice
+	x.unowned[key] = r
+	for i := range cells {
+		x.discover(cells[i], elem, r, i)
+	}
+}
+
+type byAddress []*region
+
+func (rs byAddress) Len() int      { return len(rs) }
+func (rs byAddress) Swap(i, j int) { rs[i], rs[j] = rs[j], rs[i] }
+func (rs byAddress) Less(i, j int) bool {
+	switch {
+	case rs[i].start != rs[j].start:
+		return rs[i].start < rs[j].start
+	case rs[i].n != rs[j].n:
+		return rs[i].n > rs[j].n
+	case rs[i].owned != rs[j].owned:
+		return rs[i].owned
+	default:
+		return rs[i].seq < rs[j].seq
+	}
+}
+
+// finish resolves each unowned region to the region containing it, or to
+// a new anonymous object, and converts the values of all objects.
+func (x *exporter) finish() *InitValues {
+	sorted := make([]*region, len(x.regions))
+	copy(sorted, x.regions)
+	sort.Sort(byAddress(sorted))
+
+	var top *region
+	for _, r := range sorted {
+		if top != nil && r.start < top.end() {
+			if r.end() > top.end() || r.owned {
+				abortEval("unsupported aliasing")
+			}
+			r.canon = top
+			r.offset = int((r.start - top.start) / unsafe.Sizeof(value(nil)))
+			continue
+		}
+		top = r
+	}
+
+	// The remaining unowned regions become anonymous objects, in
+	// discovery order.
+	var anon []*region
+	for _, r := range x.regions {
+		if !r.owned && r.canon == nil {
+			r.obj = &Object{Type: r.elem}
+			if r.isArray {
+				r.obj.Type = types.NewArray(r.elem, int64(r.n))
+			}
+			anon = append(anon, r)
+		}
+	}
+
+	result := &InitValues{Globals: make(map[*ssa.Global]*Object)}
+	for _, r := range x.globals {
+		r.obj.Value = x.convert(r.cells[0], r.obj.Type)
+		result.Globals[r.obj.Global] = r.obj
+		result.Objects = append(result.Objects, r.obj)
+	}
+	for _, r := range anon {
+		if r.isArray {
+			elems := make(Aggregate, r.n)
+			for i := range elems {
+				elems[i] = x.convert(r.cells[i], r.elem)
+			}
+			r.obj.Value = elems
+		} else {
+			r.obj.Value = x.convert(r.cells[0], r.elem)
+		}
+		result.Objects = append(result.Objects, r.obj)
+	}
+	result.Objects = append(result.Objects, x.extra...)
+	return result
+}
+
+// convert returns the exported representation of v, of type t.
+func (x *exporter) convert(v value, t types.Type) Value {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return v
+
+	case *types.Struct:
+		s := v.(structure)
+		elems := make(Aggregate, len(s))
+		for i := range s {
+			elems[i] = x.convert(s[i], t.Field(i).Type())
+		}
+		return elems
+
+	case *types.Array:
+		a := v.(array)
+		elems := make(Aggregate, len(a))
+		for i := range a {
+			elems[i] = x.convert(a[i], t.Elem())
+		}
+		return elems
+
+	case *types.Slice:
+		s := v.([]value)
+		switch {
+		case s == nil:
+			return Slice{}
+		case cap(s) == 0:
+			obj := &Object{Type: types.NewArray(t.Elem(), 0), Value: Aggregate{}}
+			x.extra = append(x.extra, obj)
+			return Slice{Ptr: Pointer{Obj: obj}}
+		}
+		r := x.unowned[regionKey{addrOf(&s[:cap(s)][0]), cap(s)}]
+		return Slice{Ptr: r.loc(0), Len: len(s), Cap: cap(s)}
+
+	case *types.Pointer:
+		p := v.(*value)
+		if p == nil {
+			return Pointer{}
+		}
+		return x.unowned[regionKey{addrOf(p), 1}].loc(0)
+
+	case *types.Interface:
+		iv := v.(iface)
+		if iv.t == nil {
+			return Interface{}
+		}
+		if _, ok := iv.t.Underlying().(*types.Pointer); ok {
+			return Interface{Type: iv.t, Data: x.convert(iv.v, iv.t).(Pointer)}
+		}
+		box := &Object{Type: iv.t}
+		x.extra = append(x.extra, box)
+		box.Value = x.convert(iv.v, iv.t)
+		return Interface{Type: iv.t, Data: Pointer{Obj: box}}
+
+	case *types.Signature:
+		return v.(*ssa.Function)
+
+	case *types.Map, *types.Chan:
+		// Only nil maps and channels are discovered.
+		return nil
+	}
+	panic(fmt.Sprintf("unexpected type: %s", t))
+}
@@ -84,6 +84,7 @@ type interpreter struct {
 	rtypeMethods       methodSet            // the method set of rtype, which implements the reflect.Type interface.
 	runtimeErrorString types.Type           // the runtime.errorString type
 	sizes              types.Sizes          // the effective type-sizing function
+	eval               *evalState           // non-nil during compile-time evaluation (EvalInit)
 }
 
 type deferred struct {
@@ -141,6 +142,7 @@ func (fr *frame) runDefer(d *deferred) {
 			// Deferred call created a new state of panic.
 			fr.panicking = true
 			fr.panic = recover()
+			propagateAbort(fr.panic)
 		}
 	}()
 	call(fr.i, fr, d.instr.Pos(), d.fn, d.args)
@@ -491,6 +493,9 @@ func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function,
 		caller: caller, // for panic/recover
 		fn:     fn,
 	}
+	if i.eval != nil {
+		i.eval.checkCall(fn)
+	}
 	if fn.Parent() == nil {
 		name := fn.String()
 		if ext := externals[name]; ext != nil {
@@ -552,6 +557,7 @@ func runFrame(fr *frame) {
 		}
 		fr.panicking = true
 		fr.panic = recover()
+		propagateAbort(fr.panic)
 		if fr.i.mode&EnableTracing != 0 {
 			fmt.Fprintf(os.Stderr, "Panicking: %T %v.\n", fr.panic, fr.panic)
 		}
@@ -565,6 +571,9 @@ func runFrame(fr *frame) {
 		}
 	block:
 		for _, instr := range fr.block.Instrs {
+			if fr.i.eval != nil {
+				fr.i.eval.step(instr)
+			}
 			if fr.i.mode&EnableTracing != 0 {
 				if v, ok := instr.(ssa.Value); ok {
 					fmt.Fprintln(os.Stderr, "\t", v.Name(), "=", instr)
@@ -602,9 +611,15 @@ func doRecover(caller *frame) value {
 			return p.v
 		case runtime.Error:
 			// The interpreter encountered a runtime error.
+			if caller.i.eval != nil {
+				abortEval("recovered runtime error")
+			}
 			return iface{caller.i.runtimeErrorString, p.Error()}
 		case string:
 			// The interpreter explicitly called panic().
+			if caller.i.eval != nil {
+				abortEval("recovered runtime error")
+			}
 			return iface{caller.i.runtimeErrorString, p}
 		default:
 			panic(fmt.Sprintf("unexpected panic type %T in target call to recover()", p))
@@ -47,6 +47,14 @@ cp -r $gofrontenddir/LICENSE $gofrontenddir/libgo third_party/gofrontend
 (cd third_party/gofrontend && patch -p1) < libgo-noext.diff
 # Apply a diff that disables testing of packages known to fail.
 (cd third_party/gofrontend && patch -p1) < libgo-check-failures.diff
+# Apply a diff that adds the runtime function used for fused string
+# concatenation.
+(cd third_party/gofrontend && patch -p1) < libgo-string-concat.diff
+# Apply a diff that adds the map index functions specialized by key type.
+(cd third_party/gofrontend && patch -p1) < libgo-map-fast.diff
+# Apply a diff that allocates fixed-size goroutine stacks with guard pages
+# when the runtime is built without split stacks.
+(cd third_party/gofrontend && patch -p1) < libgo-nosplit-stack.diff
 find third_party/gofrontend -name '*.orig' -exec rm \{\} \;
 
 # Remove GPL licensed files.
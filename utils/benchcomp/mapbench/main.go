@@ -0,0 +1,110 @@
+// Command mapbench measures the performance of map accesses for several
+// key types. Its output is in the format of the Go benchmark tools, so that
+// the results of running it before and after a change to llgo may be
+// compared with benchcomp.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+const mapSize = 1000
+
+var sink int
+
+func benchInt32(b *testing.B) {
+	m := make(map[int32]int)
+	for i := int32(0); i < mapSize; i++ {
+		m[i] = int(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += m[int32(i%mapSize)]
+	}
+}
+
+func benchInt64(b *testing.B) {
+	m := make(map[int64]int)
+	for i := int64(0); i < mapSize; i++ {
+		m[i] = int(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += m[int64(i%mapSize)]
+	}
+}
+
+func benchPointer(b *testing.B) {
+	keys := make([]*int, mapSize)
+	m := make(map[*int]int)
+	for i := range keys {
+		keys[i] = new(int)
+		m[keys[i]] = i
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += m[keys[i%mapSize]]
+	}
+}
+
+func benchString(b *testing.B) {
+	keys := make([]string, mapSize)
+	m := make(map[string]int)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+		m[keys[i]] = i
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += m[keys[i%mapSize]]
+	}
+}
+
+func benchBytesKey(b *testing.B) {
+	keys := make([][]byte, mapSize)
+	m := make(map[string]int)
+	for i := range keys {
+		keys[i] = []byte("key" + strconv.Itoa(i))
+		m[string(keys[i])] = i
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += m[string(keys[i%mapSize])]
+	}
+}
+
+func benchInsertInt64(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		m := make(map[int64]int)
+		for j := int64(0); j < 100; j++ {
+			m[j] = int(j)
+		}
+	}
+}
+
+var benchmarks = []struct {
+	name string
+	fn   func(*testing.B)
+}{
+	{"BenchmarkMapLookupInt32", benchInt32},
+	{"BenchmarkMapLookupInt64", benchInt64},
+	{"BenchmarkMapLookupPointer", benchPointer},
+	{"BenchmarkMapLookupString", benchString},
+	{"BenchmarkMapLookupBytesKey", benchBytesKey},
+	{"BenchmarkMapInsertInt64", benchInsertInt64},
+}
+
+func main() {
+	count := flag.Int("count", 1, "number of times to run each benchmark")
+	flag.Parse()
+
+	for i := 0; i < *count; i++ {
+		for _, bm := range benchmarks {
+			r := testing.Benchmark(bm.fn)
+			fmt.Printf("%s\t%s\t%s\n", bm.name, r.String(), r.MemString())
+		}
+	}
+}
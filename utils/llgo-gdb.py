@@ -0,0 +1,288 @@
+#===- llgo-gdb.py - gdb support for llgo programs -------------------------===#
+#
+#                     The LLVM Compiler Infrastructure
+#
+# This file is distributed under the University of Illinois Open Source
+# License. See LICENSE.TXT for details.
+#
+#===-----------------------------------------------------------------------===#
+#
+# This script provides gdb pretty-printers for the values of Go programs
+# compiled by llgo, and an "info goroutines" command. It is loaded
+# automatically by gdb for programs compiled with -g -fgdb-scripts.
+#
+# The printers depend on the layouts of strings, slices, maps, channels
+# and interfaces defined in irgen/typemap.go, and on the names given to
+# their debug info types in debug/debug.go. Both must be kept in sync
+# with this script.
+#
+#===-----------------------------------------------------------------------===#
+
+from __future__ import print_function
+
+import gdb
+
+# Type descriptor kinds, from libgo/runtime/go-type.h.
+GO_CODE_MASK = 0x1f
+GO_DIRECT_IFACE = 1 << 5
+
+# Goroutine states, from libgo/runtime/runtime.h.
+G_STATUS = {
+    0: 'idle',
+    1: 'runnable',
+    2: 'running',
+    3: 'syscall',
+    4: 'waiting',
+    5: 'moribund',
+    6: 'dead',
+}
+G_DEAD = 6
+
+
+def type_name(t):
+    return t.tag or t.name or ''
+
+
+def go_string(val):
+    """Returns the contents of the Go string val as a Python string."""
+    length = int(val['len'])
+    if length == 0:
+        return ''
+    return val['ptr'].string('utf-8', 'replace', length)
+
+
+def lookup_go_type(name):
+    """Returns the gdb type for the Go type with the given name, as
+    recorded in a type descriptor, or None if there is no such type."""
+    if name.startswith('*'):
+        t = lookup_go_type(name[1:])
+        return t and t.pointer()
+    # Named types are described by their unqualified names.
+    for candidate in (name, name[name.rfind('.') + 1:]):
+        try:
+            return gdb.lookup_type(candidate)
+        except gdb.error:
+            pass
+    return None
+
+
+class StringPrinter(object):
+    """Prints a Go string."""
+
+    def __init__(self, val):
+        self.val = val
+
+    def display_hint(self):
+        return 'string'
+
+    def to_string(self):
+        return self.val['ptr'].lazy_string(length=int(self.val['len']))
+
+
+class SlicePrinter(object):
+    """Prints a Go slice, as its elements."""
+
+    def __init__(self, val):
+        self.val = val
+
+    def display_hint(self):
+        return 'array'
+
+    def to_string(self):
+        return '%s len %d cap %d' % (type_name(self.val.type.strip_typedefs()),
+                                     int(self.val['len']),
+                                     int(self.val['cap']))
+
+    def children(self):
+        ptr = self.val['ptr']
+        for i in range(int(self.val['len'])):
+            yield '[%d]' % i, ptr[i]
+
+
+class MapPrinter(object):
+    """Prints a Go map, as its keys and values. The map is a pointer to
+    a __go_map, whose buckets are linked lists of __go_map_entry."""
+
+    def __init__(self, val):
+        self.val = val
+
+    def display_hint(self):
+        return 'map'
+
+    def to_string(self):
+        name = type_name(self.val.type.strip_typedefs())
+        if int(self.val) == 0:
+            return '%s nil' % name
+        return '%s len %d' % (name, int(self.val['__element_count']))
+
+    def children(self):
+        if int(self.val) == 0:
+            return
+        buckets = self.val['__buckets']
+        i = 0
+        for b in range(int(self.val['__bucket_count'])):
+            entry = buckets[b]
+            while int(entry) != 0:
+                yield '[%d]' % i, entry['__key']
+                yield '[%d]' % (i + 1), entry['__val']
+                i += 2
+                entry = entry['__next']
+
+
+class ChanPrinter(object):
+    """Prints a Go channel, as the elements in its buffer. The channel
+    is a pointer to a __go_channel, which is immediately followed in
+    memory by the buffer."""
+
+    def __init__(self, val):
+        self.val = val
+        self.name = type_name(val.type.strip_typedefs())
+
+    def display_hint(self):
+        return 'array'
+
+    def to_string(self):
+        if int(self.val) == 0:
+            return '%s nil' % self.name
+        s = '%s len %d cap %d' % (self.name, int(self.val['qcount']),
+                                  int(self.val['dataqsiz']))
+        if self.val['closed']:
+            s += ' (closed)'
+        return s
+
+    def elem_type(self):
+        name = self.name
+        for prefix in ('<-chan ', 'chan<- ', 'chan '):
+            if name.startswith(prefix):
+                return lookup_go_type(name[len(prefix):])
+        return None
+
+    def children(self):
+        if int(self.val) == 0:
+            return
+        elemtype = self.elem_type()
+        if elemtype is None:
+            return
+        size = int(self.val['dataqsiz'])
+        buf = (self.val + 1).cast(elemtype.pointer())
+        recvx = int(self.val['recvx'])
+        for i in range(int(self.val['qcount'])):
+            yield '[%d]' % i, buf[(recvx + i) % size]
+
+
+class InterfacePrinter(object):
+    """Prints a Go interface value, as its dynamic type and value."""
+
+    def __init__(self, val):
+        self.val = val
+
+    def type_descriptor(self):
+        if self.val.type.strip_typedefs().fields()[0].name == '__methods':
+            methods = self.val['__methods']
+            if int(methods) == 0:
+                return None
+            return methods['__type_descriptor']
+        td = self.val['__type_descriptor']
+        if int(td) == 0:
+            return None
+        return td
+
+    def to_string(self):
+        td = self.type_descriptor()
+        if td is None:
+            return 'nil'
+        name = go_string(td['__reflection'].dereference())
+        obj = self.val['__object']
+        t = lookup_go_type(name)
+        if t is None:
+            return '(%s) %s' % (name, obj)
+        try:
+            if int(td['__code']) & GO_DIRECT_IFACE:
+                value = obj.cast(t)
+            else:
+                value = obj.cast(t.pointer()).dereference()
+            return '(%s) %s' % (name, value)
+        except gdb.error:
+            return '(%s) %s' % (name, obj)
+
+
+def is_interface(t):
+    fields = t.fields()
+    return (len(fields) == 2 and
+            fields[0].name in ('__type_descriptor', '__methods') and
+            fields[1].name == '__object')
+
+
+def lookup_printer(val):
+    t = val.type.strip_typedefs()
+    name = type_name(t)
+    if t.code == gdb.TYPE_CODE_PTR:
+        if name.startswith('map['):
+            return MapPrinter(val)
+        if name.startswith('chan ') or name.startswith('<-chan ') or \
+           name.startswith('chan<- '):
+            return ChanPrinter(val)
+    elif t.code == gdb.TYPE_CODE_STRUCT:
+        if name == 'string':
+            return StringPrinter(val)
+        if name.startswith('[]'):
+            return SlicePrinter(val)
+        if is_interface(t):
+            return InterfacePrinter(val)
+    return None
+
+
+class GoroutinesCmd(gdb.Command):
+    """List the goroutines of the program.
+
+Each goroutine is listed with its ID, its state and the location of the
+go statement that created it. This requires debug info for libgo."""
+
+    def __init__(self):
+        super(GoroutinesCmd, self).__init__('info goroutines', gdb.COMMAND_STACK,
+                                            gdb.COMPLETE_NONE)
+
+    def invoke(self, arg, from_tty):
+        try:
+            allg = gdb.parse_and_eval('runtime_allg')
+            allglen = int(gdb.parse_and_eval('runtime_allglen'))
+        except gdb.error:
+            print('No goroutine information; is libgo built with debug info?')
+            return
+        for i in range(allglen):
+            g = allg[i]
+            status = int(g['status'])
+            if status == G_DEAD:
+                continue
+            state = G_STATUS.get(status, str(status))
+            if int(g['waitreason']) != 0:
+                reason = g['waitreason'].string()
+                if reason:
+                    state += ' (%s)' % reason
+            print('%5d %-20s %s' % (int(g['goid']), state,
+                                    describe_pc(int(g['gopc']))))
+
+
+def describe_pc(pc):
+    if pc == 0:
+        return ''
+    s = '0x%x' % pc
+    block = gdb.block_for_pc(pc)
+    while block is not None and block.function is None:
+        block = block.superblock
+    if block is not None:
+        s += ' in %s' % block.function.name
+    sal = gdb.find_pc_line(pc)
+    if sal.symtab is not None:
+        s += ' at %s:%d' % (sal.symtab.filename, sal.line)
+    return s
+
+
+def register(objfile):
+    if objfile is None:
+        objfile = gdb
+    objfile.pretty_printers.append(lookup_printer)
+
+
+register(gdb.current_objfile())
+GoroutinesCmd()